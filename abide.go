@@ -1,7 +1,6 @@
 package abide
 
 import (
-	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,20 +8,48 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"testing"
 )
 
 var (
 	args         *arguments
 	allSnapshots snapshots
+	// pendingSnapshots are the snapshots written to pending files in review mode.
+	pendingSnapshots = snapshots{}
+	// allSnapMutex guards allSnapshots, pendingSnapshots, dirtyPaths,
+	// assertions, tests, activeTests, results as well as the mutable
+	// fields of every snapshot they contain.
 	allSnapMutex sync.Mutex
+	// dirtyPaths are the snapshot files with changes not yet written to disk.
+	dirtyPaths = map[string]bool{}
+	// assertions are the snapshot ids asserted in this run.
+	assertions = map[snapshotID]assertion{}
+	// tests are the names of the tests which asserted a snapshot in this
+	// run, mapped to whether they ran to completion.
+	tests = map[string]bool{}
+	// results are the results of the snapshots asserted in this run.
+	results = map[snapshotID]Result{}
+	// running is set by Run, which writes every snapshot file once the
+	// tests finished.
+	running bool
+	// activeTests is the number of tests which asserted a snapshot and
+	// did not finish yet.
+	activeTests int
 )
 
 var (
 	// SnapshotsDir is the directory snapshots will be read to & written from
-	// relative directories are resolved to present-working-directory of the executing process
+	// relative directories are resolved to present-working-directory of the executing process,
+	// absolute directories may be shared by several packages
 	SnapshotsDir = "__snapshots__"
+	// ExactWhitespace causes every assertion to snapshot values byte-for-byte,
+	// by default surrounding whitespace is trimmed and ignored. See Exact to
+	// opt in for a single assertion.
+	ExactWhitespace = false
 	// snapshotsLoaded
 	snapshotsLoaded = sync.Once{}
+	// loadErr is the error encountered by loadSnapshots
+	loadErr error
 )
 
 const (
@@ -32,48 +59,244 @@ const (
 	snapshotSeparator = "/* snapshot: "
 )
 
-func init() {
-	// Get arguments
-	args = getArguments()
-}
-
 // Cleanup is an optional method which will execute cleanup operations
-// affiliated with abide testing, such as pruning snapshots.
+// affiliated with abide testing, such as pruning snapshots, reporting
+// obsolete snapshots, flushing pending snapshot writes, printing a summary
+// of the run, see CleanupWithSummary, and calling Reporters as well as the
+// reporters requested by flags. Only snapshots owned by a test which ran
+// to completion without asserting them are pruned. In strict mode, unused
+// snapshots which would be pruned are reported as an error instead.
 func Cleanup() error {
-	for _, s := range allSnapshots {
-		if !s.evaluated && args.shouldUpdate && !args.singleRun {
+	_, err := CleanupWithSummary()
+	return err
+}
+
+// CleanupWithSummary is Cleanup, additionally returning the summary of
+// the snapshots asserted, pruned and found obsolete by the run.
+func CleanupWithSummary() (Summary, error) {
+	summary := Summary{}
+	if err := loadArguments(); err != nil {
+		return summary, err
+	}
+
+	pkg, err := getTestingPackage()
+	if err != nil {
+		return summary, err
+	}
+	summary.Package = pkg
+
+	own, err := getSnapshotPath()
+	if err != nil {
+		return summary, err
+	}
+
+	// snapshots are loaded by the first assertion, which may never happen
+	if err := loadSnapshots(); err != nil {
+		return summary, err
+	}
+
+	allSnapMutex.Lock()
+
+	for _, r := range results {
+		summary.add(r)
+	}
+
+	if args.update != updateNone {
+		fmt.Printf("Snapshots written in update mode `%s`\n", &args.update)
+	}
+
+	var errs []error
+	for _, s := range allSnapshots.sorted() {
+		if args.shouldPrune && s.isUnused(own) && args.canUpdate(s.id, s.test) {
+			if args.strict {
+				errs = append(errs, fmt.Errorf("%w: refusing to remove unused snapshot %q", ErrStrictMode, s.id))
+				continue
+			}
 			s.shouldRemove = true
+			dirtyPaths[s.path] = true
+			summary.Pruned = append(summary.Pruned, string(s.id))
 			fmt.Printf("Removing unused snapshot `%s`\n", s.id)
+			continue
+		}
+
+		if s.isObsolete(own) {
+			summary.Obsolete = append(summary.Obsolete, string(s.id))
+			switch args.obsolete {
+			case obsoleteReport:
+				fmt.Printf("Obsolete snapshot `%s` is not asserted by any test\n", s.id)
+			case obsoleteFail:
+				errs = append(errs, fmt.Errorf("%w: %q is not asserted by any test", ErrObsoleteSnapshot, s.id))
+			}
+		}
+	}
+
+	summary.sort()
+	if !summary.isEmpty() {
+		fmt.Print(summary)
+	}
+
+	errs = append(errs, flush())
+	sorted := sortedResults()
+	allSnapMutex.Unlock()
+
+	// reporters may call back into abide
+	errs = append(errs, report(pkg, sorted))
+
+	return summary, joinErrors(errs...)
+}
+
+// Run runs the tests of m followed by Cleanup, and returns an exit code
+// to pass to os.Exit. A failing Cleanup fails an otherwise passing run.
+// It replaces the boilerplate of a TestMain calling Cleanup.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(abide.Run(m))
+//	}
+func Run(m *testing.M) int {
+	running = true
+	code := m.Run()
+	if err := Cleanup(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if code == 0 {
+			code = 1
 		}
 	}
+	return code
+}
+
+// Flush writes pending snapshot changes to disk. Snapshots are buffered
+// in memory and written when Flush, Cleanup or Run is called, or without
+// Run, whenever no asserting test is left running. Only files whose
+// content changed are written.
+func Flush() error {
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	return flush()
+}
+
+// flush writes the snapshot files in dirtyPaths, the caller must
+// hold allSnapMutex.
+func flush() error {
+	if len(dirtyPaths) == 0 {
+		return nil
+	}
 
-	return allSnapshots.save()
+	err := allSnapshots.saveFiles(dirtyPaths)
+	if err != nil {
+		return err
+	}
+
+	err = pendingSnapshots.saveFiles(dirtyPaths)
+	if err != nil {
+		return err
+	}
+
+	dirtyPaths = map[string]bool{}
+	return nil
 }
 
 // snapshotID represents the unique identifier for a snapshot.
 type snapshotID string
 
 // isValid verifies whether the snapshotID is valid. An
-// identifier is considered invalid if it is already in use
-// or it is malformed.
+// identifier is considered invalid if it is empty or it
+// cannot be represented in a snapshot file header.
 func (s *snapshotID) isValid() bool {
-	return true
+	id := string(*s)
+	if strings.TrimSpace(id) == "" {
+		return false
+	}
+
+	return !strings.ContainsAny(id, "\r\n") && !strings.Contains(id, snapshotSeparator)
+}
+
+// assertion records the test which first asserted a snapshot id in
+// this run, and the value it asserted.
+type assertion struct {
+	test  string
+	value string
+}
+
+// recordAssertion records that test asserted value for id. Asserting
+// the same id from a different test with a different value is an error,
+// as both tests would share, and overwrite, a single snapshot.
+func recordAssertion(id snapshotID, test, value string) error {
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	prev, ok := assertions[id]
+	if !ok {
+		assertions[id] = assertion{test: test, value: value}
+		return nil
+	}
+
+	if prev.test != test && prev.value != value {
+		return fmt.Errorf("%w: %q is asserted with different values by %s and %s", ErrDuplicateSnapshotID, id, prev.test, test)
+	}
+	return nil
 }
 
 // snapshot represents the expected value of a test, identified by an id.
 type snapshot struct {
-	id           snapshotID
-	value        string
+	id    snapshotID
+	value string
+	// test is the name of the test owning the snapshot, empty if unknown.
+	test         string
 	path         string
 	evaluated    bool
 	shouldRemove bool
+	// dirty marks a snapshot created or updated since it was last saved.
+	dirty bool
+}
+
+// isUnused reports whether s, stored in the snapshot file own of the
+// package under test, was not asserted although it would have been: its
+// owning test ran to completion in this run, or, for a snapshot without
+// an owner, every test of the package ran. Snapshots of skipped tests,
+// tests which failed or did not run are never unused, nor are those of
+// other packages sharing SnapshotsDir. The caller must hold allSnapMutex.
+func (s *snapshot) isUnused(own string) bool {
+	if s.evaluated || s.path != own {
+		return false
+	}
+	if s.test == "" {
+		return !args.singleRun
+	}
+	return tests[s.test]
+}
+
+// isObsolete reports whether s, stored in the snapshot file own of the
+// package under test, was not asserted by a full, unfiltered run, unless
+// its owning test was skipped or failed. Unlike isUnused, snapshots of
+// tests which did not run at all, e.g. because they were removed, are
+// obsolete. The caller must hold allSnapMutex.
+func (s *snapshot) isObsolete(own string) bool {
+	if s.evaluated || s.path != own || args.singleRun || args.filter != nil {
+		return false
+	}
+	completed, ran := tests[s.test]
+	return !ran || completed
 }
 
 // snapshots represents a map of snapshots by id.
 type snapshots map[snapshotID]*snapshot
 
-// save writes all snapshots to their designated files.
-func (s snapshots) save() error {
+// sorted returns the snapshots ordered by id.
+func (s snapshots) sorted() []*snapshot {
+	sorted := make([]*snapshot, 0, len(s))
+	for _, snap := range s {
+		sorted = append(sorted, snap)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].id < sorted[j].id
+	})
+	return sorted
+}
+
+// saveFiles writes the snapshots designated to the given paths, or
+// to every path if paths is nil.
+func (s snapshots) saveFiles(paths map[string]bool) error {
 	snapshotsByPath := map[string][]*snapshot{}
 	for _, snap := range s {
 		_, ok := snapshotsByPath[snap.path]
@@ -84,23 +307,11 @@ func (s snapshots) save() error {
 	}
 
 	for path, snaps := range snapshotsByPath {
-		if path == "" {
+		if path == "" || (paths != nil && !paths[path]) {
 			continue
 		}
 
-		snapshotMap := snapshots{}
-		for _, snap := range snaps {
-			if snap.shouldRemove {
-				continue
-			}
-			snapshotMap[snap.id] = snap
-		}
-		data, err := encode(snapshotMap)
-		if err != nil {
-			return err
-		}
-
-		err = ioutil.WriteFile(path, data, 0666)
+		err := writeSnapshotFile(path, snaps)
 		if err != nil {
 			return err
 		}
@@ -109,63 +320,13 @@ func (s snapshots) save() error {
 	return nil
 }
 
-// decode decides a slice of bytes to retrieve a Snapshots object.
-func decode(data []byte) (snapshots, error) {
-	snaps := make(snapshots)
-
-	snapshotsStr := strings.Split(string(data), snapshotSeparator)
-	for _, s := range snapshotsStr {
-		if s == "" {
-			continue
-		}
-
-		components := strings.SplitAfterN(s, "\n", 2)
-		id := snapshotID(strings.TrimSuffix(components[0], " */\n"))
-		val := strings.TrimSpace(components[1])
-		snaps[id] = &snapshot{
-			id:    id,
-			value: val,
-		}
-	}
-
-	return snaps, nil
-}
-
-// encode encodes a snapshots object into a slice of bytes.
-func encode(snaps snapshots) ([]byte, error) {
-	var buf bytes.Buffer
-	var err error
-
-	ids := []string{}
-	for id := range snaps {
-		ids = append(ids, string(id))
-	}
-
-	sort.Strings(ids)
-
-	for _, id := range ids {
-		s := snaps[snapshotID(id)]
-
-		_, err = buf.WriteString(fmt.Sprintf("%s%s */\n", snapshotSeparator, string(s.id)))
-		if err != nil {
-			return nil, err
-		}
-		_, err = buf.WriteString(fmt.Sprintf("%s\n\n", s.value))
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return bytes.TrimSpace(buf.Bytes()), nil
-}
-
 // loadSnapshots loads all snapshots in the current directory, can only
-// be called once
-func loadSnapshots() (err error) {
+// be called once, subsequent calls return the error of the first.
+func loadSnapshots() error {
 	snapshotsLoaded.Do(func() {
-		err = reloadSnapshots()
+		loadErr = reloadSnapshots()
 	})
-	return err
+	return loadErr
 }
 
 // reloadSnapshots overwrites allSnapshots internal
@@ -178,7 +339,7 @@ func reloadSnapshots() error {
 
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %v", ErrUnableToReadSnapshotDirectory, err)
 	}
 
 	paths := []string{}
@@ -189,123 +350,188 @@ func reloadSnapshots() error {
 		}
 	}
 
-	allSnapshots, err = parseSnapshotsFromPaths(paths)
+	snaps, err := parseSnapshotsFromPaths(paths)
+
+	allSnapMutex.Lock()
+	allSnapshots = snaps
+	dirtyPaths = map[string]bool{}
+	allSnapMutex.Unlock()
+
 	return err
 }
 
-// getSnapshot retrieves a snapshot by id.
-func getSnapshot(id snapshotID) *snapshot {
+// evaluateSnapshot retrieves a snapshot by id and marks it as evaluated.
+func evaluateSnapshot(id snapshotID) (*snapshot, error) {
 	if err := loadSnapshots(); err != nil {
-		panic(err)
+		return nil, err
 	}
-	return allSnapshots[id]
-}
 
-// createSnapshot creates a Snapshot.
-func createSnapshot(id snapshotID, value string) (*snapshot, error) {
-	return writeSnapshot(id, value, false)
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	s := allSnapshots[id]
+	if s != nil {
+		s.evaluated = true
+	}
+	return s, nil
 }
 
-// updateSnapshot creates a Snapshot.
-func updateSnapshot(id snapshotID, value string) (*snapshot, error) {
-	return writeSnapshot(id, value, true)
+// updateSnapshot updates a Snapshot owned by test.
+func updateSnapshot(id snapshotID, value, test string) (*snapshot, error) {
+	return writeSnapshot(id, value, test, true)
 }
 
-// writeSnapshot creates or updates a Snapshot.
-func writeSnapshot(id snapshotID, value string, isUpdate bool) (*snapshot, error) {
+// writeSnapshot creates or updates a Snapshot owned by test. An updated
+// snapshot stays in the file it was loaded from, and keeps its previous
+// owner without a test.
+func writeSnapshot(id snapshotID, value, test string, evaluated bool) (*snapshot, error) {
 	if !id.isValid() {
-		return nil, errInvalidSnapshotID
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSnapshotID, id)
 	}
 
 	if err := loadSnapshots(); err != nil {
 		return nil, err
 	}
 
-	dir, err := findOrCreateSnapshotDirectory()
-	if err != nil {
-		return nil, err
-	}
-
-	pkg, err := getTestingPackage()
+	path, err := getSnapshotPath()
 	if err != nil {
 		return nil, err
 	}
 
-	path := filepath.Join(dir, fmt.Sprintf("%s%s", pkg, snapshotExt))
-
 	s := &snapshot{
 		id:        id,
 		value:     value,
+		test:      test,
 		path:      path,
-		evaluated: isUpdate,
+		evaluated: evaluated,
+		dirty:     true,
 	}
 
 	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	if prev, ok := allSnapshots[id]; ok {
+		s.path = prev.path
+		if test == "" {
+			s.test = prev.test
+		}
+	}
 	allSnapshots[id] = s
-	allSnapMutex.Unlock()
+	dirtyPaths[s.path] = true
+
+	return s, nil
+}
 
-	err = allSnapshots.save()
+// claimSnapshot records test as the owner of the snapshot identified by
+// id, if test was the first to assert it in this run.
+func claimSnapshot(id snapshotID, test string) {
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	s := allSnapshots[id]
+	if s == nil || s.test == test || assertions[id].test != test {
+		return
+	}
+
+	s.test = test
+	s.dirty = true
+	dirtyPaths[s.path] = true
+}
+
+// getSnapshotPath returns the path of the snapshot file new snapshots
+// of the package under test are written to.
+func getSnapshotPath() (string, error) {
+	dir, err := findOrCreateSnapshotDirectory()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	return s, nil
+	pkg, err := getTestingPackage()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s%s", pkg, snapshotExt)), nil
 }
 
 func findOrCreateSnapshotDirectory() (string, error) {
 	testingPath, err := getTestingPath()
 	if err != nil {
-		return "", errUnableToLocateTestPath
+		return "", ErrUnableToLocateTestPath
+	}
+
+	dir := SnapshotsDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(testingPath, dir)
 	}
 
-	dir := filepath.Join(testingPath, SnapshotsDir)
 	_, err = os.Stat(dir)
 	if os.IsNotExist(err) {
+		// parallel tests may race to create the directory
 		err = os.Mkdir(dir, os.ModePerm)
-		if err != nil {
-			return "", errUnableToCreateSnapshotDirectory
+		if err != nil && !os.IsExist(err) {
+			return "", ErrUnableToCreateSnapshotDirectory
 		}
 	}
 
 	return dir, nil
 }
 
+// parseSnapshotsFromPaths decodes the snapshot files at paths. Errors
+// are collected for every file which cannot be read or decoded, the
+// snapshots of the remaining files are still returned.
 func parseSnapshotsFromPaths(paths []string) (snapshots, error) {
 	var snaps = make(snapshots)
-	var mutex = &sync.Mutex{}
+	var decoded = make([]snapshots, len(paths))
+	var errs = make([]error, len(paths))
 
 	var wg sync.WaitGroup
 	for i := range paths {
 		wg.Add(1)
-		go func(p string) {
+		go func(i int, p string) {
 			defer wg.Done()
 
-			file, err := os.Open(p)
-			if err != nil {
-				return
-			}
-
-			data, err := ioutil.ReadAll(file)
+			data, err := ioutil.ReadFile(p)
 			if err != nil {
+				errs[i] = &FileError{Path: p, Err: fmt.Errorf("%w: %v", ErrUnreadableSnapshotFile, err)}
 				return
 			}
 
 			s, err := decode(data)
 			if err != nil {
+				errs[i] = withPath(p, err)
 				return
 			}
 
-			mutex.Lock()
-			for id, snap := range s {
-				snap.path = p
-				snaps[id] = snap
-			}
-			mutex.Unlock()
-		}(paths[i])
+			decoded[i] = s
+		}(i, paths[i])
 	}
 	wg.Wait()
 
-	return snaps, nil
+	// merge in order of paths, so collisions are reported deterministically
+	for i, p := range paths {
+		ids := make([]string, 0, len(decoded[i]))
+		for id := range decoded[i] {
+			ids = append(ids, string(id))
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			snap := decoded[i][snapshotID(id)]
+			if other, ok := snaps[snap.id]; ok {
+				errs = append(errs, &FileError{
+					Path: p,
+					Err:  fmt.Errorf("%w: %q is also defined in %s", ErrDuplicateSnapshotID, id, other.path),
+				})
+				continue
+			}
+
+			snap.path = p
+			snaps[snap.id] = snap
+		}
+	}
+
+	return snaps, joinErrors(errs...)
 }
 
 func getTestingPath() (string, error) {
@@ -0,0 +1,135 @@
+package abide
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// stressWorkers is the number of parallel subtests spawned by each stress test.
+const stressWorkers = 50
+
+func TestStressCreate(t *testing.T) {
+	defer testingCleanup()
+
+	withArguments(updateAll, true, func() {
+		t.Run("group", func(t *testing.T) {
+			for i := 0; i < stressWorkers; i++ {
+				i := i
+				t.Run(strconv.Itoa(i), func(t *testing.T) {
+					t.Parallel()
+					id := fmt.Sprintf("create %d", i)
+					createOrUpdateSnapshot(t, id, id, false)
+				})
+			}
+		})
+	})
+
+	for i := 0; i < stressWorkers; i++ {
+		id := fmt.Sprintf("create %d", i)
+		s := getSnapshot(snapshotID(id))
+		if s == nil {
+			t.Fatalf("Expected snapshot[%s] to exist.", id)
+		}
+		if s.value != id {
+			t.Fatalf("Expected snapshot[%s] to be %q, instead got %q.", id, id, s.value)
+		}
+	}
+}
+
+func TestStressCompare(t *testing.T) {
+	defer testingCleanup()
+
+	for i := 0; i < stressWorkers; i++ {
+		id := fmt.Sprintf("compare %d", i)
+		_ = testingSnapshot(id, id)
+	}
+
+	withArguments(updateNone, true, func() {
+		t.Run("group", func(t *testing.T) {
+			for i := 0; i < stressWorkers; i++ {
+				i := i
+				t.Run(strconv.Itoa(i), func(t *testing.T) {
+					t.Parallel()
+					// every worker compares every snapshot
+					for j := 0; j < stressWorkers; j++ {
+						id := fmt.Sprintf("compare %d", (i+j)%stressWorkers)
+						createOrUpdateSnapshot(t, id, id, false)
+					}
+				})
+			}
+		})
+	})
+
+	for i := 0; i < stressWorkers; i++ {
+		id := fmt.Sprintf("compare %d", i)
+		if s := getSnapshot(snapshotID(id)); s == nil || !s.evaluated {
+			t.Fatalf("Expected snapshot[%s] to be evaluated.", id)
+		}
+	}
+}
+
+func TestStressUpdate(t *testing.T) {
+	defer testingCleanup()
+
+	for i := 0; i < stressWorkers; i++ {
+		id := fmt.Sprintf("update %d", i)
+		_ = testingSnapshot(id, "stale")
+	}
+
+	withArguments(updateAll, true, func() {
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+
+		// cleanup and saving race against the assertions below
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					if err := Cleanup(); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+
+		t.Run("group", func(t *testing.T) {
+			for i := 0; i < stressWorkers; i++ {
+				i := i
+				t.Run(strconv.Itoa(i), func(t *testing.T) {
+					t.Parallel()
+					id := fmt.Sprintf("update %d", i)
+					createOrUpdateSnapshot(t, id, id, false)
+					createOrUpdateSnapshot(t, id, id, false)
+				})
+			}
+		})
+
+		close(done)
+		wg.Wait()
+	})
+
+	err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < stressWorkers; i++ {
+		id := fmt.Sprintf("update %d", i)
+		s := getSnapshot(snapshotID(id))
+		if s == nil || s.value != id {
+			t.Fatalf("Expected snapshot[%s] to be updated.", id)
+		}
+	}
+}
@@ -1,13 +1,65 @@
 package abide
 
 import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if err := loadArguments(); err != nil {
+		panic(err)
+	}
+	// the suite creates and updates snapshots, also when run on CI
+	args.strict = false
+	os.Exit(m.Run())
+}
+
 func testingCleanup() {
 	os.RemoveAll(SnapshotsDir)
+
+	allSnapMutex.Lock()
+	allSnapshots = snapshots{}
+	pendingSnapshots = snapshots{}
+	dirtyPaths = map[string]bool{}
+	assertions = map[snapshotID]assertion{}
+	tests = map[string]bool{}
+	activeTests = 0
+	results = map[snapshotID]Result{}
+	allSnapMutex.Unlock()
+}
+
+// save writes all snapshots to their designated files. When s is
+// allSnapshots, the caller must hold allSnapMutex.
+func (s snapshots) save() error {
+	return s.saveFiles(nil)
+}
+
+// getSnapshot retrieves a snapshot by id.
+func getSnapshot(id snapshotID) *snapshot {
+	if err := loadSnapshots(); err != nil {
+		panic(err)
+	}
+
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	return allSnapshots[id]
+}
+
+// createSnapshot creates a Snapshot.
+func createSnapshot(id snapshotID, value string) (*snapshot, error) {
+	return writeSnapshot(id, value, "", false)
 }
 
 func testingSnapshot(id, value string) *snapshot {
@@ -22,18 +74,29 @@ func testingSnapshot(id, value string) *snapshot {
 func testingSnapshots(count int) snapshots {
 	s := make(snapshots, count)
 	for i := 0; i < count; i++ {
-		id := string(i)
+		id := strconv.Itoa(i)
 		s[snapshotID(id)] = testingSnapshot(id, id)
 	}
 	return s
 }
 
+func withArguments(update updateMode, singleRun bool, fn func()) {
+	prev := *args
+	args.update, args.singleRun = update, singleRun
+	args.shouldPrune = update == updateAll
+	defer func() {
+		*args = prev
+	}()
+
+	fn()
+}
+
 func TestCleanup(t *testing.T) {
 	defer testingCleanup()
 
 	_ = testingSnapshot("1", "A")
 
-	// If shouldUpdate = false, the snapshot must remain.
+	// If update = none, the snapshot must remain.
 	err := Cleanup()
 	if err != nil {
 		t.Fatal(err)
@@ -49,8 +112,9 @@ func TestCleanup(t *testing.T) {
 		t.Fatal("Expected snapshot[1] to exist.")
 	}
 
-	// If shouldUpdate = true and singleRun = false, the snapshot must be removed.
-	args.shouldUpdate = true
+	// If update = all and singleRun = false, the snapshot must be removed.
+	args.update = updateAll
+	args.shouldPrune = true
 	args.singleRun = false
 	err = Cleanup()
 	if err != nil {
@@ -69,13 +133,279 @@ func TestCleanup(t *testing.T) {
 	}
 }
 
+func TestCleanupStrict(t *testing.T) {
+	defer testingCleanup()
+
+	_ = testingSnapshot("1", "A")
+	err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withArguments(updateAll, false, func() {
+		args.strict = true
+		err = Cleanup()
+	})
+	if !errors.Is(err, ErrStrictMode) {
+		t.Fatalf("Expected ErrStrictMode, instead got %v.", err)
+	}
+
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getSnapshot("1") == nil {
+		t.Fatal("Expected snapshot[1] to remain.")
+	}
+}
+
+func TestCleanupOwnership(t *testing.T) {
+	defer testingCleanup()
+
+	owned := map[string]string{
+		"completed": "TestCompleted",
+		"asserted":  "TestCompleted",
+		"skipped":   "TestSkipped",
+		"unrun":     "TestUnrun",
+	}
+	for id, test := range owned {
+		_, err := writeSnapshot(snapshotID(id), id, test, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = evaluateSnapshot("asserted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests["TestCompleted"] = true
+	tests["TestSkipped"] = false
+
+	// owned snapshots are pruned in single runs too
+	withArguments(updateAll, true, func() {
+		err = Cleanup()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id := range owned {
+		if (getSnapshot(snapshotID(id)) == nil) != (id == "completed") {
+			t.Errorf("Expected only snapshot[completed] to be removed, snapshot[%s] was not.", id)
+		}
+	}
+	if s := getSnapshot("asserted"); s != nil && s.test != "TestCompleted" {
+		t.Fatalf("Expected the owner to be saved, instead got %q.", s.test)
+	}
+}
+
+func TestCleanupObsolete(t *testing.T) {
+	defer testingCleanup()
+
+	owned := map[string]string{
+		"asserted": "TestAsserted",
+		"skipped":  "TestSkipped",
+		"removed":  "TestRemoved",
+		"legacy":   "",
+	}
+	for id, test := range owned {
+		_, err := writeSnapshot(snapshotID(id), id, test, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := evaluateSnapshot("asserted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests["TestAsserted"] = true
+	tests["TestSkipped"] = false
+
+	cleanup := func(obsolete obsoleteMode, singleRun bool) error {
+		var err error
+		withArguments(updateNone, singleRun, func() {
+			args.obsolete = obsolete
+			err = Cleanup()
+		})
+		return err
+	}
+
+	err = cleanup(obsoleteFail, false)
+	if !errors.Is(err, ErrObsoleteSnapshot) {
+		t.Fatalf("Expected ErrObsoleteSnapshot, instead got %v.", err)
+	}
+	for id := range owned {
+		if strings.Contains(err.Error(), strconv.Quote(id)) != (id == "removed" || id == "legacy") {
+			t.Errorf("Unexpected obsolete snapshots for %s: %v", id, err)
+		}
+	}
+
+	// filtered runs cannot tell whether a snapshot is obsolete
+	err = cleanup(obsoleteFail, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cleanup(obsoleteReport, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// obsolete snapshots are reported, never removed
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getSnapshot("removed") == nil {
+		t.Fatal("Expected snapshot[removed] to remain.")
+	}
+}
+
+func TestCleanupWithoutAssertions(t *testing.T) {
+	defer testingCleanup()
+
+	path, err := getSnapshotPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = modifySnapshotFile(path, false, func(snaps snapshots) {
+		snaps["stale"] = &snapshot{id: "stale", value: "A"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a run in which no test asserted a snapshot has not loaded them yet
+	allSnapMutex.Lock()
+	allSnapshots = snapshots{}
+	allSnapMutex.Unlock()
+	snapshotsLoaded = sync.Once{}
+
+	withArguments(updateNone, false, func() {
+		args.obsolete = obsoleteFail
+		err = Cleanup()
+	})
+	if !errors.Is(err, ErrObsoleteSnapshot) {
+		t.Fatalf("Expected ErrObsoleteSnapshot, instead got %v.", err)
+	}
+}
+
+func TestCleanupSharedDirectory(t *testing.T) {
+	defer testingCleanup()
+
+	_ = testingSnapshot("legacy", "A")
+	err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the snapshots of another package sharing the directory
+	dir, err := findOrCreateSnapshotDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(dir, "other"+snapshotExt)
+	err = modifySnapshotFile(other, false, func(snaps snapshots) {
+		snaps["other legacy"] = &snapshot{id: "other legacy", value: "B"}
+		snaps["other owned"] = &snapshot{id: "other owned", value: "C", test: "TestCompleted"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests["TestCompleted"] = true
+
+	// the snapshots of another package are never obsolete
+	withArguments(updateNone, false, func() {
+		args.obsolete = obsoleteFail
+		err = Cleanup()
+	})
+	if !errors.Is(err, ErrObsoleteSnapshot) || strings.Contains(err.Error(), "other") {
+		t.Fatalf("Expected only snapshot[legacy] to be obsolete, instead got %v.", err)
+	}
+
+	withArguments(updateAll, false, func() {
+		err = Cleanup()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getSnapshot("legacy") != nil {
+		t.Fatal("Expected snapshot[legacy] to be removed.")
+	}
+	for _, id := range []snapshotID{"other legacy", "other owned"} {
+		if getSnapshot(id) == nil {
+			t.Errorf("Expected snapshot[%s] of another package to remain.", id)
+		}
+	}
+}
+
+func TestUpdateSnapshotInOtherFile(t *testing.T) {
+	defer testingCleanup()
+
+	dir, err := findOrCreateSnapshotDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(dir, "other"+snapshotExt)
+	err = modifySnapshotFile(other, false, func(snaps snapshots) {
+		snaps["x"] = &snapshot{id: "x", value: "A"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withArguments(updateAll, true, func() {
+		_, err = match("x", "B", false, "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a copy in the file of the package would be a duplicate id
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := getSnapshot("x"); s == nil || s.value != "B" || s.path != other {
+		t.Fatalf("Expected snapshot[x] to be updated in %s, instead got %+v.", other, s)
+	}
+}
+
 func TestCleanupUpdate(t *testing.T) {
 	defer testingCleanup()
 
 	// this snapshot is updated, should be evaluated, and not removed
 	_ = testingSnapshot("1", "A")
 	t2 := &testing.T{}
-	createOrUpdateSnapshot(t2, "1", "B")
+	createOrUpdateSnapshot(t2, "1", "B", false)
 
 	// this snapshot is never evaluated, and should be removed
 	_ = testingSnapshot("2", "B")
@@ -85,8 +415,9 @@ func TestCleanupUpdate(t *testing.T) {
 		t.Fatal("Expected snapshot[1] to exist.")
 	}
 
-	// If shouldUpdate = true and singleRun = false, the snapshot must be removed.
-	args.shouldUpdate = true
+	// If update = all and singleRun = false, the snapshot must be removed.
+	args.update = updateAll
+	args.shouldPrune = true
 	args.singleRun = false
 	err := Cleanup()
 	if err != nil {
@@ -110,9 +441,71 @@ func TestCleanupUpdate(t *testing.T) {
 }
 
 func TestSnapshotIDIsValid(t *testing.T) {
-	id := snapshotID("1")
-	if !id.isValid() {
-		t.Fatalf("Expected true, instead got %t.", id.isValid())
+	cases := map[snapshotID]bool{
+		"1":                         true,
+		"route /users */":           true,
+		"":                          false,
+		"  ":                        false,
+		"multi\nline":               false,
+		"carriage\rreturn":          false,
+		"nested /* snapshot: id":    false,
+		"TestUsers/admin/1":         true,
+		"with trailing whitespace ": true,
+	}
+
+	for id, expected := range cases {
+		if id.isValid() != expected {
+			t.Errorf("Expected isValid(%q) to be %t, instead got %t.", id, expected, id.isValid())
+		}
+	}
+}
+
+func TestDuplicateAssertions(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("1", "A")
+
+	withArguments(updateNone, true, func() {
+		t.Run("first", func(t *testing.T) {
+			createOrUpdateSnapshot(t, "1", "A", false)
+		})
+
+		t2 := &testing.T{}
+		createOrUpdateSnapshot(t2, "1", "A", false)
+		if t2.Failed() {
+			t.Fatal("Expected the same value asserted by another test to pass.")
+		}
+
+		t3 := &testing.T{}
+		createOrUpdateSnapshot(t3, "1", "B", false)
+		if !t3.Failed() {
+			t.Fatal("Expected a different value asserted by another test to fail.")
+		}
+	})
+}
+
+func TestLoadDuplicateSnapshots(t *testing.T) {
+	defer testingCleanup()
+
+	dir, err := findOrCreateSnapshotDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := filepath.Join(dir, "a"+snapshotExt)
+	b := filepath.Join(dir, "b"+snapshotExt)
+	for _, path := range []string{a, b} {
+		err = ioutil.WriteFile(path, []byte("/* snapshot: 1 */\nA"), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = reloadSnapshots()
+	if !errors.Is(err, ErrDuplicateSnapshotID) {
+		t.Fatalf("Expected ErrDuplicateSnapshotID, instead got %v.", err)
+	}
+	if !strings.Contains(err.Error(), a) || !strings.Contains(err.Error(), b) {
+		t.Fatalf("Expected error to name %s and %s, instead got %v.", a, b, err)
 	}
 }
 
@@ -133,6 +526,74 @@ func TestSnapshotsSave(t *testing.T) {
 	}
 }
 
+func TestFlush(t *testing.T) {
+	defer testingCleanup()
+
+	dir, err := findOrCreateSnapshotDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an unrelated snapshot file must not be rewritten
+	otherPath := filepath.Join(dir, "other"+snapshotExt)
+	otherData := []byte("/* snapshot: other */\nvalue")
+	err = ioutil.WriteFile(otherPath, otherData, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(otherPath, past, past)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := testingSnapshot("1", "A")
+	if _, err := os.Stat(s.path); !os.IsNotExist(err) {
+		t.Fatal("Expected snapshot file to be written on flush only.")
+	}
+
+	err = Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(s.path); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Fatalf("Expected %s to be left untouched.", otherPath)
+	}
+
+	// flushing without changes must not rewrite the file
+	err = os.Chtimes(s.path, past, past)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = testingSnapshot("1", "A")
+	err = Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = os.Stat(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Fatalf("Expected unchanged %s to be left untouched.", s.path)
+	}
+}
+
 func TestLoadSnapshots(t *testing.T) {
 	defer testingCleanup()
 
@@ -159,23 +620,72 @@ func TestLoadSnapshots(t *testing.T) {
 	}
 }
 
-func TestGetSnapshot(t *testing.T) {
+func TestLoadSnapshotsErrors(t *testing.T) {
 	defer testingCleanup()
 
-	snapshot := testingSnapshot("3", "C")
-	if !reflect.DeepEqual(snapshot, getSnapshot(snapshot.id)) {
-		t.Fatal("Failed to fetch snapshot correctly.")
+	dir, err := findOrCreateSnapshotDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := filepath.Join(dir, "valid"+snapshotExt)
+	corrupt := filepath.Join(dir, "corrupt"+snapshotExt)
+	unreadable := filepath.Join(dir, "unreadable"+snapshotExt)
+	corruptData := []byte("/* snapshot: 1 */\nA\n\n/* snapshot: 2")
+
+	err = ioutil.WriteFile(valid, []byte("/* snapshot: 3 */\nC"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(corrupt, corruptData, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(unreadable, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloadSnapshots()
+	if !errors.Is(err, ErrCorruptSnapshotFile) {
+		t.Fatalf("Expected ErrCorruptSnapshotFile, instead got %v.", err)
+	}
+	if !errors.Is(err, ErrUnreadableSnapshotFile) {
+		t.Fatalf("Expected ErrUnreadableSnapshotFile, instead got %v.", err)
+	}
+
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) {
+		t.Fatalf("Expected a FileError, instead got %v.", err)
+	}
+	if !strings.Contains(err.Error(), corrupt+":4:") {
+		t.Fatalf("Expected error to locate %s:4, instead got %v.", corrupt, err)
+	}
+
+	if getSnapshot("3") == nil {
+		t.Fatal("Expected snapshots of valid files to be loaded.")
+	}
+
+	// a file which failed to load must not be overwritten
+	err = writeSnapshotFile(corrupt, []*snapshot{{id: "1", value: "B", dirty: true}})
+	if !errors.Is(err, ErrCorruptSnapshotFile) {
+		t.Fatalf("Expected ErrCorruptSnapshotFile, instead got %v.", err)
+	}
+
+	data, err := ioutil.ReadFile(corrupt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, corruptData) {
+		t.Fatal("Expected corrupt snapshot file to be left untouched.")
 	}
 }
 
-func benchmarkEncode(count int, b *testing.B) {
+func TestGetSnapshot(t *testing.T) {
 	defer testingCleanup()
-	s := testingSnapshots(count)
-	for i := 0; i < b.N; i++ {
-		encode(s)
+
+	snapshot := testingSnapshot("3", "C")
+	if !reflect.DeepEqual(snapshot, getSnapshot(snapshot.id)) {
+		t.Fatal("Failed to fetch snapshot correctly.")
 	}
 }
-
-func BenchmarkEncode10(b *testing.B)   { benchmarkEncode(10, b) }
-func BenchmarkEncode100(b *testing.B)  { benchmarkEncode(100, b) }
-func BenchmarkEncode1000(b *testing.B) { benchmarkEncode(1000, b) }
@@ -1,31 +1,280 @@
 package abide
 
 import (
+	"flag"
+	"fmt"
 	"os"
-	"strings"
+	"regexp"
+	"strconv"
+	"sync"
 )
 
+var (
+	// argsLoaded ensures the arguments are read once.
+	argsLoaded sync.Once
+	// argsErr is the error encountered reading the arguments.
+	argsErr error
+)
+
+func init() {
+	// flags are read through the flag package once it parsed them,
+	// see getArguments
+	registerFlags(flag.CommandLine)
+}
+
+// registerFlags defines the abide flags on fs.
+func registerFlags(fs *flag.FlagSet) {
+	fs.Var(new(updateMode), "abide.update", "create missing snapshots, update those which do not match and prune unused snapshots; restricted by `mode` new or mismatched, or review to write pending files instead; or set ABIDE_UPDATE")
+	fs.Bool("abide.prune", false, "prune unused snapshots, or set ABIDE_PRUNE=1")
+	fs.String("abide.filter", "", "only create, update or prune snapshots whose id or test name matches `regexp`, or set ABIDE_FILTER")
+	fs.Var(new(obsoleteMode), "abide.obsolete", "`report` or fail on snapshots not asserted by a full run, or set ABIDE_OBSOLETE")
+	fs.String("abide.results", "", "write the results of the run as JSON to `path`, relative to the package directory, or set ABIDE_RESULTS")
+	fs.String("abide.junit", "", "write the results of the run as JUnit XML to `path`, relative to the package directory, or set ABIDE_JUNIT")
+	fs.String("abide.tap", "", "write the results of the run as TAP to `path`, relative to the package directory, or set ABIDE_TAP")
+	fs.Bool("abide.strict", false, "fail instead of creating, updating or pruning snapshots, or set ABIDE_STRICT; enabled when CI=true")
+}
+
+// updateMode determines which snapshots are written by a run.
+type updateMode int
+
+const (
+	// updateNone writes no snapshots.
+	updateNone updateMode = iota
+	// updateNew only creates missing snapshots.
+	updateNew
+	// updateMismatched only updates snapshots which do not match.
+	updateMismatched
+	// updateAll creates, updates and prunes snapshots.
+	updateAll
+	// updateReview writes missing and mismatched snapshots to pending
+	// files, leaving them to be accepted or rejected by cmd/abide.
+	updateReview
+)
+
+// String returns the name of the mode.
+func (m *updateMode) String() string {
+	switch *m {
+	case updateNew:
+		return "new"
+	case updateMismatched:
+		return "mismatched"
+	case updateAll:
+		return "all"
+	case updateReview:
+		return "review"
+	default:
+		return "none"
+	}
+}
+
+// Set parses the name of a mode, or a boolean selecting all or none.
+func (m *updateMode) Set(s string) error {
+	switch s {
+	case "new":
+		*m = updateNew
+	case "mismatched":
+		*m = updateMismatched
+	case "all":
+		*m = updateAll
+	case "review":
+		*m = updateReview
+	case "none":
+		*m = updateNone
+	default:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid update mode %q, expected new, mismatched, all or review", s)
+		}
+		*m = updateNone
+		if b {
+			*m = updateAll
+		}
+	}
+	return nil
+}
+
+// IsBoolFlag allows -abide.update to be passed without a mode.
+func (m *updateMode) IsBoolFlag() bool {
+	return true
+}
+
+// shouldCreate reports whether missing snapshots are created.
+func (m updateMode) shouldCreate() bool {
+	return m == updateNew || m == updateAll
+}
+
+// shouldUpdate reports whether mismatched snapshots are updated.
+func (m updateMode) shouldUpdate() bool {
+	return m == updateMismatched || m == updateAll
+}
+
+// obsoleteMode determines how Cleanup treats obsolete snapshots.
+type obsoleteMode int
+
+const (
+	// obsoleteIgnore ignores obsolete snapshots.
+	obsoleteIgnore obsoleteMode = iota
+	// obsoleteReport prints obsolete snapshots.
+	obsoleteReport
+	// obsoleteFail returns an error for obsolete snapshots.
+	obsoleteFail
+)
+
+// String returns the name of the mode.
+func (m *obsoleteMode) String() string {
+	switch *m {
+	case obsoleteReport:
+		return "report"
+	case obsoleteFail:
+		return "fail"
+	default:
+		return "ignore"
+	}
+}
+
+// Set parses the name of a mode.
+func (m *obsoleteMode) Set(s string) error {
+	switch s {
+	case "ignore":
+		*m = obsoleteIgnore
+	case "report":
+		*m = obsoleteReport
+	case "fail":
+		*m = obsoleteFail
+	default:
+		return fmt.Errorf("invalid obsolete mode %q, expected ignore, report or fail", s)
+	}
+	return nil
+}
+
 type arguments struct {
-	shouldUpdate bool
-	singleRun    bool
+	update      updateMode
+	shouldPrune bool
+	singleRun   bool
+	// filter restricts updates to matching snapshot ids or test names.
+	filter *regexp.Regexp
+	// strict refuses to write snapshots, failing the assertion instead.
+	strict bool
+	// obsolete determines how Cleanup treats obsolete snapshots.
+	obsolete obsoleteMode
+	// resultsPath is the path the results of the run are written to as JSON.
+	resultsPath string
+	// junitPath is the path the results of the run are written to as JUnit XML.
+	junitPath string
+	// tapPath is the path the results of the run are written to as TAP.
+	tapPath string
 }
 
-func getArguments() *arguments {
-	args := &arguments{}
-	for _, arg := range os.Args {
-		argList := strings.Split(arg, "=")
-		if len(argList) > 0 {
-			arg = argList[0]
+// loadArguments reads the arguments of the run, it must be called
+// after the testing package has parsed the command line.
+func loadArguments() error {
+	argsLoaded.Do(func() {
+		args, argsErr = getArguments(flag.CommandLine)
+	})
+	return argsErr
+}
+
+// canUpdate reports whether the snapshot identified by id, asserted
+// by test, may be created, updated or pruned.
+func (a *arguments) canUpdate(id snapshotID, test string) bool {
+	if a.filter == nil {
+		return true
+	}
+	return a.filter.MatchString(string(id)) || (test != "" && a.filter.MatchString(test))
+}
+
+// getArguments reads the namespaced abide flags of fs, falling back to their
+// environment variable equivalents so updates can be requested for many
+// packages at once. The legacy `-u` flag is honored when it follows `--`,
+// as in `go test -- -u`. Strict mode is enabled on CI, as detected by
+// CI=true, unless disabled explicitly.
+func getArguments(fs *flag.FlagSet) (*arguments, error) {
+	args := &arguments{
+		shouldPrune: boolArgument(fs, "abide.prune", "ABIDE_PRUNE"),
+		resultsPath: stringArgument(fs, "abide.results", "ABIDE_RESULTS"),
+		junitPath:   stringArgument(fs, "abide.junit", "ABIDE_JUNIT"),
+		tapPath:     stringArgument(fs, "abide.tap", "ABIDE_TAP"),
+		singleRun:   flagValue(fs, "test.run") != "" || flagValue(fs, "test.skip") != "",
+	}
+
+	if update := stringArgument(fs, "abide.update", "ABIDE_UPDATE"); update != "" {
+		err := args.update.Set(update)
+		if err != nil {
+			return args, err
+		}
+	}
+
+	if hasLegacyUpdate(fs.Args()) {
+		args.update = updateAll
+	}
+
+	// updating everything implies pruning
+	if args.update == updateAll {
+		args.shouldPrune = true
+	}
+
+	if filter := stringArgument(fs, "abide.filter", "ABIDE_FILTER"); filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return args, fmt.Errorf("invalid -abide.filter: %w", err)
 		}
-		switch arg {
-		case "-u":
-			args.shouldUpdate = true
-			break
-		case "-test.run":
-			args.singleRun = true
-			break
+		args.filter = re
+	}
+
+	if obsolete := stringArgument(fs, "abide.obsolete", "ABIDE_OBSOLETE"); obsolete != "" {
+		err := args.obsolete.Set(obsolete)
+		if err != nil {
+			return args, err
 		}
 	}
 
-	return args
+	if strict := stringArgument(fs, "abide.strict", "ABIDE_STRICT"); strict != "" {
+		b, err := strconv.ParseBool(strict)
+		if err != nil {
+			return args, fmt.Errorf("invalid -abide.strict: %w", err)
+		}
+		args.strict = b
+	} else {
+		args.strict, _ = strconv.ParseBool(os.Getenv("CI"))
+	}
+
+	return args, nil
+}
+
+// hasLegacyUpdate reports whether the positional arguments contain `-u`.
+func hasLegacyUpdate(positional []string) bool {
+	for _, arg := range positional {
+		if arg == "-u" {
+			return true
+		}
+	}
+	return false
+}
+
+// boolArgument returns the boolean value of stringArgument.
+func boolArgument(fs *flag.FlagSet, name, env string) bool {
+	b, _ := strconv.ParseBool(stringArgument(fs, name, env))
+	return b
+}
+
+// stringArgument returns the value of the named flag if it was set on
+// fs, or else of the environment variable env.
+func stringArgument(fs *flag.FlagSet, name, env string) string {
+	value := os.Getenv(env)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			value = f.Value.String()
+		}
+	})
+	return value
+}
+
+// flagValue returns the value of the named flag of fs, or an empty
+// string if it is not defined.
+func flagValue(fs *flag.FlagSet, name string) string {
+	f := fs.Lookup(name)
+	if f == nil {
+		return ""
+	}
+	return f.Value.String()
 }
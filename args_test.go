@@ -1,21 +1,205 @@
 package abide
 
 import (
+	"errors"
+	"flag"
 	"os"
 	"testing"
 )
 
+// setenv sets the environment variable key to value until t finishes,
+// like t.Setenv, which requires Go 1.17.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// newFlagSet returns a flag set defining the abide flags, parsed from
+// arguments, so tests leave flag.CommandLine untouched.
+func newFlagSet(t *testing.T, arguments ...string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	registerFlags(fs)
+	if err := fs.Parse(arguments); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
 func TestGetArguments(t *testing.T) {
 	// test without update
-	args := getArguments()
-	if args.shouldUpdate {
-		t.Fatalf("Expected false, instead got %t", args.shouldUpdate)
+	args, err := getArguments(newFlagSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.update != updateNone {
+		t.Fatalf("Expected none, instead got %s", &args.update)
+	}
+
+	// test with environment variables
+	setenv(t, "ABIDE_UPDATE", "1")
+	setenv(t, "ABIDE_PRUNE", "true")
+	args, err = getArguments(newFlagSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.update != updateAll || !args.shouldPrune {
+		t.Fatalf("Expected all and true, instead got %s and %t", &args.update, args.shouldPrune)
+	}
+
+	// test flags taking precedence over environment variables
+	args, err = getArguments(newFlagSet(t, "-abide.update=new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.update != updateNew {
+		t.Fatalf("Expected new, instead got %s", &args.update)
+	}
+}
+
+func TestGetArgumentsStrict(t *testing.T) {
+	setenv(t, "CI", "true")
+	args, err := getArguments(newFlagSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !args.strict {
+		t.Fatal("Expected strict mode on CI.")
+	}
+
+	setenv(t, "ABIDE_STRICT", "0")
+	args, err = getArguments(newFlagSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.strict {
+		t.Fatal("Expected ABIDE_STRICT=0 to disable strict mode on CI.")
+	}
+
+	setenv(t, "ABIDE_STRICT", "maybe")
+	_, err = getArguments(newFlagSet(t))
+	if err == nil {
+		t.Fatal("Expected an error for an invalid ABIDE_STRICT.")
+	}
+}
+
+func TestStrictBootstrap(t *testing.T) {
+	defer testingCleanup()
+	setenv(t, "CI", "true")
+	setenv(t, "ABIDE_UPDATE", "new")
+
+	// creating snapshots on CI requires disabling strict mode explicitly
+	cases := []struct {
+		strict string
+		status Status
+		err    error
+	}{
+		{"", StatusNew, ErrStrictMode},
+		{"0", StatusCreated, nil},
+	}
+	for _, c := range cases {
+		setenv(t, "ABIDE_STRICT", c.strict)
+		parsed, err := getArguments(newFlagSet(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prev := *args
+		*args = *parsed
+		result, err := Match("bootstrap", String("A"))
+		*args = prev
+
+		if !errors.Is(err, c.err) || result.Status != c.status {
+			t.Fatalf("Expected %s and %v with ABIDE_STRICT=%q, instead got %s and %v.", c.status, c.err, c.strict, result.Status, err)
+		}
+	}
+}
+
+func TestGetArgumentsObsolete(t *testing.T) {
+	setenv(t, "ABIDE_OBSOLETE", "fail")
+	args, err := getArguments(newFlagSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.obsolete != obsoleteFail {
+		t.Fatalf("Expected fail, instead got %s", &args.obsolete)
+	}
+
+	setenv(t, "ABIDE_OBSOLETE", "delete")
+	_, err = getArguments(newFlagSet(t))
+	if err == nil {
+		t.Fatal("Expected an error for an unknown obsolete mode.")
+	}
+}
+
+func TestUpdateModeSet(t *testing.T) {
+	cases := map[string]updateMode{
+		"true":       updateAll,
+		"1":          updateAll,
+		"all":        updateAll,
+		"false":      updateNone,
+		"none":       updateNone,
+		"new":        updateNew,
+		"mismatched": updateMismatched,
+		"review":     updateReview,
+	}
+
+	for value, expected := range cases {
+		var m updateMode
+		err := m.Set(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != expected {
+			t.Errorf("Expected %s for %q, instead got %s", &expected, value, &m)
+		}
+	}
+
+	var m updateMode
+	if m.Set("everything") == nil {
+		t.Fatal("Expected an error for an unknown mode.")
+	}
+}
+
+func TestHasLegacyUpdate(t *testing.T) {
+	if !hasLegacyUpdate([]string{"-u"}) {
+		t.Fatal("Expected -u following -- to request an update.")
+	}
+	if hasLegacyUpdate([]string{"-user=foo"}) {
+		t.Fatal("Expected -user not to request an update.")
+	}
+}
+
+func TestGetArgumentsFilter(t *testing.T) {
+	setenv(t, "ABIDE_FILTER", "^users/")
+	args, err := getArguments(newFlagSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !args.canUpdate("users/admin", "TestPosts") {
+		t.Fatal("Expected a matching id to be updatable.")
+	}
+	if !args.canUpdate("admin", "users/TestAdmin") {
+		t.Fatal("Expected a matching test name to be updatable.")
+	}
+	if args.canUpdate("posts/admin", "TestPosts") {
+		t.Fatal("Expected other snapshots not to be updatable.")
 	}
 
-	// test with update flag
-	os.Args = append(os.Args, "-u")
-	args = getArguments()
-	if !args.shouldUpdate {
-		t.Fatalf("Expected true, instead got %t", args.shouldUpdate)
+	setenv(t, "ABIDE_FILTER", "(")
+	_, err = getArguments(newFlagSet(t))
+	if err == nil {
+		t.Fatal("Expected an error for an invalid filter.")
 	}
 }
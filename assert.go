@@ -2,31 +2,79 @@ package abide
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"strings"
-	"testing"
+	"sync"
 
 	"github.com/beme/abide/internal"
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
+var (
+	// autoIDs counts the AssertAuto calls by test name.
+	autoIDs      = map[string]int{}
+	autoIDsMutex sync.Mutex
+)
+
+// TestingT is the subset of testing.TB used to report assertions, allowing
+// snapshots to be asserted from tests, benchmarks, fuzz targets and custom
+// test harnesses alike.
+type TestingT interface {
+	Helper()
+	Error(args ...interface{})
+	Fatal(args ...interface{})
+	Name() string
+	Cleanup(func())
+}
+
 // Assertable represents an object that can be asserted.
 type Assertable interface {
 	String() string
 }
 
 // Assert asserts the value of an object with implements Assertable.
-func Assert(t *testing.T, id string, a Assertable) {
+func Assert(t TestingT, id string, a Assertable) {
+	t.Helper()
 	data := a.String()
-	createOrUpdateSnapshot(t, id, data)
+	_, exact := a.(exactAssertable)
+	createOrUpdateSnapshot(t, id, data, exact)
+}
+
+// AssertAuto asserts the value of an object which implements Assertable.
+// The snapshot is identified by the name of the test, followed by the number
+// of AssertAuto calls made by the test so far, e.g. `TestUsers/admin/1`.
+func AssertAuto(t TestingT, a Assertable) {
+	t.Helper()
+	Assert(t, autoID(t), a)
+}
+
+// autoID returns the next automatic snapshot id of t. Counters are
+// reset once t finishes, so ids are stable across repeated runs.
+func autoID(t TestingT) string {
+	autoIDsMutex.Lock()
+	defer autoIDsMutex.Unlock()
+
+	name := t.Name()
+	if autoIDs[name] == 0 {
+		t.Cleanup(func() {
+			autoIDsMutex.Lock()
+			delete(autoIDs, name)
+			autoIDsMutex.Unlock()
+		})
+	}
+	autoIDs[name]++
+
+	return fmt.Sprintf("%s/%d", name, autoIDs[name])
 }
 
 // AssertHTTPResponse asserts the value of an http.Response.
-func AssertHTTPResponse(t *testing.T, id string, w *http.Response) {
+func AssertHTTPResponse(t TestingT, id string, w *http.Response) {
+	t.Helper()
 	body, err := httputil.DumpResponse(w, true)
 	if err != nil {
 		t.Fatal(err)
@@ -38,7 +86,8 @@ func AssertHTTPResponse(t *testing.T, id string, w *http.Response) {
 // AssertHTTPRequestOut asserts the value of an http.Request.
 // Intended for use when testing outgoing client requests
 // See https://golang.org/pkg/net/http/httputil/#DumpRequestOut for more
-func AssertHTTPRequestOut(t *testing.T, id string, r *http.Request) {
+func AssertHTTPRequestOut(t TestingT, id string, r *http.Request) {
+	t.Helper()
 	body, err := httputil.DumpRequestOut(r, true)
 	if err != nil {
 		t.Fatal(err)
@@ -50,7 +99,8 @@ func AssertHTTPRequestOut(t *testing.T, id string, r *http.Request) {
 // AssertHTTPRequest asserts the value of an http.Request.
 // Intended for use when testing incoming client requests
 // See https://golang.org/pkg/net/http/httputil/#DumpRequest for more
-func AssertHTTPRequest(t *testing.T, id string, r *http.Request) {
+func AssertHTTPRequest(t TestingT, id string, r *http.Request) {
+	t.Helper()
 	body, err := httputil.DumpRequest(r, true)
 	if err != nil {
 		t.Fatal(err)
@@ -59,7 +109,8 @@ func AssertHTTPRequest(t *testing.T, id string, r *http.Request) {
 	assertHTTP(t, id, body, contentTypeIsJSON(r.Header.Get("Content-Type")))
 }
 
-func assertHTTP(t *testing.T, id string, body []byte, isJSON bool) {
+func assertHTTP(t TestingT, id string, body []byte, isJSON bool) {
+	t.Helper()
 	config, err := getConfig()
 	if err != nil {
 		t.Fatal(err)
@@ -107,7 +158,7 @@ func assertHTTP(t *testing.T, id string, body []byte, isJSON bool) {
 	}
 
 	data = strings.Join(lines, "\n")
-	createOrUpdateSnapshot(t, id, data)
+	createOrUpdateSnapshot(t, id, data, false)
 }
 
 func contentTypeIsJSON(contentType string) bool {
@@ -127,52 +178,89 @@ func contentTypeIsJSON(contentType string) bool {
 }
 
 // AssertReader asserts the value of an io.Reader.
-func AssertReader(t *testing.T, id string, r io.Reader) {
+func AssertReader(t TestingT, id string, r io.Reader) {
+	t.Helper()
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	createOrUpdateSnapshot(t, id, string(data))
+	createOrUpdateSnapshot(t, id, string(data), false)
 }
 
-func createOrUpdateSnapshot(t *testing.T, id, data string) {
-	var err error
-	snapshot := getSnapshot(snapshotID(id))
+// createOrUpdateSnapshot compares data to the snapshot identified by id,
+// creating or updating the snapshot when requested, and reports the
+// result through t.
+func createOrUpdateSnapshot(t TestingT, id, data string, exact bool) {
+	t.Helper()
+	trackCompletion(t)
+	result, err := match(snapshotID(id), data, exact, t.Name())
+	if errors.Is(err, ErrDuplicateSnapshotID) {
+		t.Error(err)
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
 
-	if snapshot == nil {
-		if !args.shouldUpdate {
-			t.Error(newSnapshotMessage(id, data))
-			return
-		}
+	switch result.Status {
+	case StatusNew:
+		t.Error(newSnapshotMessage(id, result.Actual, result.Pending))
+	case StatusMismatched:
+		t.Error(didNotMatchMessage(id, compareResults(result.Stored, result.Actual), result.Pending))
+	}
+}
 
-		fmt.Printf("Creating snapshot `%s`\n", id)
-		snapshot, err = createSnapshot(snapshotID(id), data)
-		if err != nil {
-			t.Fatal(err)
-		}
-		snapshot.evaluated = true
+// testStatus is implemented by testing.TB, reporting how a test finished.
+type testStatus interface {
+	Failed() bool
+	Skipped() bool
+}
+
+// trackCompletion records whether t runs to completion, neither failing
+// nor being skipped, once it finishes. Only the unasserted snapshots of
+// completed tests are pruned, harnesses without Failed and Skipped never
+// complete. Unless Run is in use, pending snapshot changes are flushed
+// whenever no asserting test is left running, so packages without a
+// TestMain persist them, also when only subtests assert.
+func trackCompletion(t TestingT) {
+	name := t.Name()
+	if name == "" {
 		return
 	}
 
-	snapshot.evaluated = true
-	diff := compareResults(t, snapshot.value, strings.TrimSpace(data))
-	if diff != "" {
-		if snapshot != nil && args.shouldUpdate {
-			fmt.Printf("Updating snapshot `%s`\n", id)
-			_, err = updateSnapshot(snapshotID(id), data)
-			if err != nil {
-				t.Fatal(err)
-			}
-			return
-		}
-
-		t.Error(didNotMatchMessage(id, diff))
+	allSnapMutex.Lock()
+	_, tracked := tests[name]
+	if !tracked {
+		tests[name] = false
+		activeTests++
+	}
+	allSnapMutex.Unlock()
+	if tracked {
 		return
 	}
+
+	t.Cleanup(func() {
+		status, ok := t.(testStatus)
+		completed := ok && !status.Failed() && !status.Skipped()
+
+		allSnapMutex.Lock()
+		tests[name] = completed
+		activeTests--
+		idle := activeTests == 0
+		allSnapMutex.Unlock()
+
+		if running || !idle {
+			return
+		}
+		if err := Flush(); err != nil {
+			t.Error(err)
+		}
+	})
 }
 
-func compareResults(t *testing.T, existing, new string) string {
+func compareResults(existing, new string) string {
 	dmp := diffmatchpatch.New()
 	dmp.PatchMargin = 20
 	allDiffs := dmp.DiffMain(existing, new, false)
@@ -190,20 +278,54 @@ func compareResults(t *testing.T, existing, new string) string {
 	return dmp.DiffPrettyText(allDiffs)
 }
 
-func didNotMatchMessage(id, diff string) string {
+// plainDiff returns a diff of existing and new without colors, marking
+// deletions as [-text-] and insertions as {+text+}, or an empty string if
+// they are equal. Results and reports are read outside of terminals, see
+// compareResults for the colored diff printed by tests.
+func plainDiff(existing, new string) string {
+	if existing == new {
+		return ""
+	}
+
+	dmp := diffmatchpatch.New()
+	var b strings.Builder
+	for _, d := range dmp.DiffMain(existing, new, false) {
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			b.WriteString("[-" + d.Text + "-]")
+		case diffmatchpatch.DiffInsert:
+			b.WriteString("{+" + d.Text + "+}")
+		default:
+			b.WriteString(d.Text)
+		}
+	}
+	return b.String()
+}
+
+func didNotMatchMessage(id, diff string, pending bool) string {
 	msg := "\n\n## Existing snapshot does not match results...\n"
 	msg += "## \"" + id + "\"\n\n"
 	msg += diff
 	msg += "\n\n"
-	msg += "If this change was intentional, run tests again, $ go test -v -- -u\n"
+	if pending {
+		msg += pendingMessage
+	} else {
+		msg += "If this change was intentional, run tests again, $ go test -v -args -abide.update\n"
+	}
 	return msg
 }
 
-func newSnapshotMessage(id, body string) string {
+func newSnapshotMessage(id, body string, pending bool) string {
 	msg := "\n\n## New snapshot found...\n"
 	msg += "## \"" + id + "\"\n\n"
 	msg += body
 	msg += "\n\n"
-	msg += "To save, run tests again, $ go test -v -- -u\n"
+	if pending {
+		msg += pendingMessage
+	} else {
+		msg += "To save, run tests again, $ go test -v -args -abide.update\n"
+	}
 	return msg
 }
+
+const pendingMessage = "Written to a pending snapshot file for review, $ abide list\n"
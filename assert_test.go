@@ -1,6 +1,8 @@
 package abide
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -27,3 +29,173 @@ func TestContentTypeIsJSON(test *testing.T) {
 		}
 	}
 }
+
+func TestAssertExact(t *testing.T) {
+	defer testingCleanup()
+	withArguments(updateAll, true, func() {
+		Assert(t, "exact", Exact(String("\n  indented\r\nvalue\n\n")))
+		Assert(t, "trimmed", String("\n  indented\r\nvalue\n\n"))
+	})
+
+	err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := getSnapshot("exact"); s == nil || s.value != "\n  indented\r\nvalue\n\n" {
+		t.Fatalf("Expected exact snapshot to round-trip, instead got %+v.", s)
+	}
+	if s := getSnapshot("trimmed"); s == nil || s.value != "indented\nvalue" {
+		t.Fatalf("Expected trimmed snapshot, instead got %+v.", s)
+	}
+
+	withArguments(updateNone, true, func() {
+		t2 := &testing.T{}
+		Assert(t2, "exact", Exact(String("\n  indented\r\nvalue\n")))
+		if !t2.Failed() {
+			t.Fatal("Expected a missing trailing newline to fail an exact assertion.")
+		}
+
+		t3 := &testing.T{}
+		Assert(t3, "trimmed", String("indented\r\nvalue\n"))
+		if t3.Failed() {
+			t.Fatal("Expected surrounding whitespace to be ignored.")
+		}
+	})
+}
+
+func TestAssertAuto(t *testing.T) {
+	defer testingCleanup()
+
+	withArguments(updateAll, true, func() {
+		for _, name := range []string{"admin", "guest"} {
+			t.Run(name, func(t *testing.T) {
+				AssertAuto(t, String(name+" first"))
+				AssertAuto(t, String(name+" second"))
+			})
+		}
+	})
+
+	expected := map[snapshotID]string{
+		"TestAssertAuto/admin/1": "admin first",
+		"TestAssertAuto/admin/2": "admin second",
+		"TestAssertAuto/guest/1": "guest first",
+		"TestAssertAuto/guest/2": "guest second",
+	}
+	for id, value := range expected {
+		if s := getSnapshot(id); s == nil || s.value != value {
+			t.Fatalf("Expected snapshot[%s] to be %q, instead got %+v.", id, value, s)
+		}
+	}
+
+	// counters restart for every run of a test
+	autoIDsMutex.Lock()
+	defer autoIDsMutex.Unlock()
+	if len(autoIDs) != 0 {
+		t.Fatalf("Expected counters to be reset, instead got %v.", autoIDs)
+	}
+}
+
+// harness is a custom test harness implementing TestingT.
+type harness struct {
+	name    string
+	errors  []string
+	helpers int
+}
+
+func (h *harness) Helper()                   { h.helpers++ }
+func (h *harness) Error(args ...interface{}) { h.errors = append(h.errors, fmt.Sprint(args...)) }
+func (h *harness) Fatal(args ...interface{}) { h.Error(args...) }
+func (h *harness) Name() string              { return h.name }
+func (h *harness) Cleanup(func())            {}
+
+func TestAssertTestingT(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("harness", "A")
+
+	withArguments(updateNone, true, func() {
+		h := &harness{name: "harness"}
+		Assert(h, "harness", String("A"))
+		if len(h.errors) != 0 {
+			t.Fatalf("Expected no errors, instead got %v.", h.errors)
+		}
+		if h.helpers == 0 {
+			t.Fatal("Expected Helper to be called.")
+		}
+
+		Assert(h, "harness", String("B"))
+		if len(h.errors) != 1 {
+			t.Fatalf("Expected 1 error, instead got %v.", h.errors)
+		}
+	})
+}
+
+func TestTrackCompletion(t *testing.T) {
+	defer testingCleanup()
+
+	t.Run("completed", func(t *testing.T) {
+		trackCompletion(t)
+		trackCompletion(t)
+	})
+	t.Run("skipped", func(t *testing.T) {
+		trackCompletion(t)
+		t.Skip()
+	})
+	// the cleanup of the harness is never run
+	trackCompletion(&harness{name: "harness"})
+
+	expected := map[string]bool{
+		t.Name() + "/completed": true,
+		t.Name() + "/skipped":   false,
+		"harness":               false,
+	}
+	if !reflect.DeepEqual(expected, tests) {
+		t.Fatalf("Expected tracked tests %v, instead got %v.", expected, tests)
+	}
+}
+
+func BenchmarkAssert(b *testing.B) {
+	defer testingCleanup()
+	_ = testingSnapshot("benchmark", "A")
+
+	withArguments(updateNone, true, func() {
+		for i := 0; i < b.N; i++ {
+			Assert(b, "benchmark", String("A"))
+		}
+	})
+}
+
+func TestTrackCompletionFlush(t *testing.T) {
+	defer testingCleanup()
+
+	path, err := getSnapshotPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without Run, the snapshots of subtests are written once they finish
+	withArguments(updateAll, true, func() {
+		t.Run("table", func(t *testing.T) {
+			for _, name := range []string{"admin", "guest"} {
+				t.Run(name, func(t *testing.T) {
+					AssertAuto(t, String(name))
+				})
+			}
+		})
+	})
+
+	snaps, err := readSnapshotFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"admin", "guest"} {
+		id := snapshotID(t.Name() + "/table/" + name + "/1")
+		if s, ok := snaps[id]; !ok || s.value != name {
+			t.Fatalf("Expected snapshot[%s] to be written, instead got %v.", id, snaps)
+		}
+	}
+}
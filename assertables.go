@@ -20,3 +20,13 @@ func Interface(i interface{}) Assertable {
 	// include the type in the string that is asserted to avoid suprises
 	return assertableString(fmt.Sprintf("%T %+v", i, i))
 }
+
+type exactAssertable struct {
+	Assertable
+}
+
+// Exact is syntactic sugar. It is a helper that marks an Assertable to be snapshotted
+// byte-for-byte, including surrounding whitespace, trailing newlines and line endings.
+func Exact(a Assertable) Assertable {
+	return exactAssertable{a}
+}
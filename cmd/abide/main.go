@@ -0,0 +1,180 @@
+// Command abide reviews the pending snapshot changes written by tests run
+// in review mode, `go test -args -abide.update=review`.
+//
+// Usage:
+//
+//	abide [-dir path] list
+//	abide [-dir path] review
+//	abide [-dir path] accept [-all] [id ...]
+//	abide [-dir path] reject [-all] [id ...]
+//
+// The list command prints every pending change with its diff. The review
+// command shows the changes one at a time, prompting to accept, reject,
+// skip, or edit each in $EDITOR before accepting it. The accept command
+// merges the pending changes of the given snapshot ids into their snapshot
+// files, the reject command discards them. Either applies to every pending
+// change with -all.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/beme/abide"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const usage = `usage: abide [-dir path] <command> [arguments]
+
+commands:
+  list                     list pending changes with their diffs
+  review                   review pending changes one at a time
+  accept [-all] [id ...]   accept pending changes into the snapshot files
+  reject [-all] [id ...]   discard pending changes
+`
+
+// errUsage is returned when the command line is invalid.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command line args, reading answers to prompts from
+// stdin and printing to stdout and stderr.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("abide", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	dir := fs.String("dir", ".", "search `path` and its subdirectories for pending changes")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	changes, err := abide.PendingChanges(*dir)
+	if err != nil {
+		return err
+	}
+
+	switch cmd := fs.Arg(0); cmd {
+	case "list":
+		return list(changes, stdout)
+	case "review":
+		return review(changes, stdin, stdout)
+	case "accept", "reject":
+		selected, err := selectChanges(changes, fs.Args()[1:], stderr)
+		if err != nil {
+			return err
+		}
+		return resolve(cmd, selected, stdout)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n", cmd)
+		fs.Usage()
+		return errUsage
+	}
+}
+
+// list prints changes with their diffs.
+func list(changes []abide.PendingChange, w io.Writer) error {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No pending snapshot changes.")
+		return nil
+	}
+
+	for _, c := range changes {
+		printChange(w, c)
+	}
+	fmt.Fprintf(w, "%d pending snapshot changes.\n", len(changes))
+	return nil
+}
+
+// selectChanges parses the arguments of accept and reject, returning
+// the changes of the given ids, or every change with -all.
+func selectChanges(changes []abide.PendingChange, args []string, stderr io.Writer) ([]abide.PendingChange, error) {
+	fs := flag.NewFlagSet("abide", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	all := fs.Bool("all", false, "apply to every pending change")
+	if err := fs.Parse(args); err != nil {
+		return nil, errUsage
+	}
+
+	if *all {
+		return changes, nil
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprint(stderr, "no snapshot ids given, use -all to apply to every pending change\n\n")
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	byID := map[string][]abide.PendingChange{}
+	for _, c := range changes {
+		byID[c.ID] = append(byID[c.ID], c)
+	}
+
+	selected := []abide.PendingChange{}
+	for _, id := range fs.Args() {
+		c, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("no pending change for snapshot %q", id)
+		}
+		selected = append(selected, c...)
+	}
+	return selected, nil
+}
+
+// resolve accepts or rejects changes, as named by cmd.
+func resolve(cmd string, changes []abide.PendingChange, w io.Writer) error {
+	var err error
+	if cmd == "accept" {
+		err = abide.AcceptPending(changes...)
+	} else {
+		err = abide.RejectPending(changes...)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		fmt.Fprintf(w, "%sed %q in %s\n", title(cmd), c.ID, c.Path)
+	}
+	return nil
+}
+
+// printChange prints c with its diff, colored for terminals.
+func printChange(w io.Writer, c abide.PendingChange) {
+	dmp := diffmatchpatch.New()
+	diff := dmp.DiffPrettyText(dmp.DiffMain(c.Stored, c.Pending, false))
+
+	fmt.Fprintf(w, "## %q (%s) in %s\n\n", c.ID, describe(c), c.Path)
+	fmt.Fprintf(w, "%s\n\n", diff)
+}
+
+// describe returns whether c creates or updates its snapshot.
+func describe(c abide.PendingChange) string {
+	if c.New {
+		return "new"
+	}
+	return "mismatched"
+}
+
+// title capitalizes the ASCII command name s.
+func title(s string) string {
+	return string(s[0]-'a'+'A') + s[1:]
+}
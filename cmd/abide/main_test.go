@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testSnapshots = "/* abide: v2 */\n\n/* snapshot: first */\nA\n\n/* snapshot: second */\nA\n\n/* abide: end */\n"
+	testPending   = "/* abide: v2 */\n\n/* snapshot: first */\nB\n\n/* snapshot: second */\nB\n\n/* snapshot: third */\nC\n\n/* abide: end */\n"
+)
+
+func testingDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "abide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "__snapshots__", "pkg.snapshot")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(testSnapshots), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path+".new", []byte(testPending), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestList(t *testing.T) {
+	dir := testingDir(t)
+
+	var stdout bytes.Buffer
+	err := run([]string{"-dir", dir, "list"}, nil, &stdout, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := stdout.String()
+	for _, expected := range []string{`"first" (mismatched)`, `"second" (mismatched)`, `"third" (new)`, "3 pending snapshot changes."} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected output to contain %q, instead got:\n%s", expected, out)
+		}
+	}
+}
+
+func TestAcceptReject(t *testing.T) {
+	dir := testingDir(t)
+	path := filepath.Join(dir, "__snapshots__", "pkg.snapshot")
+
+	err := run([]string{"-dir", dir, "accept", "first", "third"}, nil, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "/* abide: v3 */\n\n/* snapshot: first */\nB\n\n/* snapshot: second */\nA\n\n/* snapshot: third */\nC\n\n/* abide: end */\n"
+	if string(data) != expected {
+		t.Fatalf("Expected accepted snapshots %q, instead got %q.", expected, data)
+	}
+
+	err = run([]string{"-dir", dir, "reject", "-all"}, nil, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".new"); !os.IsNotExist(err) {
+		t.Fatalf("Expected the pending file to be removed, instead got %v.", err)
+	}
+
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Fatalf("Expected rejecting to leave snapshots untouched, instead got %q.", data)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	dir := testingDir(t)
+
+	cases := [][]string{
+		{},
+		{"-dir", dir, "approve"},
+		{"-dir", dir, "accept"},
+	}
+	for _, args := range cases {
+		err := run(args, nil, ioutil.Discard, ioutil.Discard)
+		if !errors.Is(err, errUsage) {
+			t.Errorf("Expected a usage error for %q, instead got %v.", args, err)
+		}
+	}
+
+	err := run([]string{"-dir", dir, "accept", "fourth"}, nil, ioutil.Discard, ioutil.Discard)
+	if err == nil || errors.Is(err, errUsage) {
+		t.Errorf("Expected an error for an unknown id, instead got %v.", err)
+	}
+}
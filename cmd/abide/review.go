@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/beme/abide"
+)
+
+const prompt = "[a]ccept, [r]eject, [s]kip, [e]dit then accept, [q]uit? "
+
+// review prompts for a decision on each of changes in turn, reading the
+// answers from r. Decisions are written as soon as they are made, so
+// quitting or reaching the end of r keeps the changes reviewed so far.
+func review(changes []abide.PendingChange, r io.Reader, w io.Writer) error {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No pending snapshot changes.")
+		return nil
+	}
+
+	in := bufio.NewReader(r)
+	var accepted, rejected int
+
+loop:
+	for i, c := range changes {
+		fmt.Fprintf(w, "[%d/%d] ", i+1, len(changes))
+		printChange(w, c)
+
+		for {
+			fmt.Fprint(w, prompt)
+			answer, err := in.ReadString('\n')
+			if err != nil && (err != io.EOF || answer == "") {
+				fmt.Fprintln(w)
+				break loop
+			}
+
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "a":
+				err = abide.AcceptPending(c)
+				accepted++
+			case "r":
+				err = abide.RejectPending(c)
+				rejected++
+			case "s":
+			case "e":
+				c.Pending, err = edit(c.Pending)
+				if err != nil {
+					return err
+				}
+				err = abide.AcceptPending(c)
+				accepted++
+			case "q":
+				break loop
+			default:
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			break
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Accepted %d, rejected %d of %d pending snapshot changes.\n", accepted, rejected, len(changes))
+	return nil
+}
+
+// edit opens value in $EDITOR, or vi if unset, and returns the edited
+// value. The final newline added by most editors is removed.
+func edit(value string) (string, error) {
+	f, err := ioutil.TempFile("", "abide-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(value + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor: %w", err)
+	}
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
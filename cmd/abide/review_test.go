@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setenv sets the environment variable key to value until t finishes,
+// like t.Setenv, which requires Go 1.17.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// TestHelperEditor is not a real test, it is executed as $EDITOR by
+// TestReview, replacing the content of the edited file.
+func TestHelperEditor(t *testing.T) {
+	value := os.Getenv("ABIDE_HELPER_EDITOR")
+	if value == "" {
+		return
+	}
+
+	err := ioutil.WriteFile(flag.Arg(0), []byte(value+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReview(t *testing.T) {
+	dir := testingDir(t)
+	path := filepath.Join(dir, "__snapshots__", "pkg.snapshot")
+
+	setenv(t, "EDITOR", os.Args[0]+" -test.run=^TestHelperEditor$ --")
+	setenv(t, "ABIDE_HELPER_EDITOR", "D")
+
+	// an unknown answer prompts again
+	stdin := strings.NewReader("x\na\ns\ne\n")
+	var stdout bytes.Buffer
+	err := run([]string{"-dir", dir, "review"}, stdin, &stdout, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := stdout.String()
+	if n := strings.Count(out, prompt); n != 4 {
+		t.Fatalf("Expected 4 prompts, instead got %d:\n%s", n, out)
+	}
+	if !strings.Contains(out, "Accepted 2, rejected 0 of 3") {
+		t.Fatalf("Expected a summary of the decisions, instead got:\n%s", out)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "/* abide: v3 */\n\n/* snapshot: first */\nB\n\n/* snapshot: second */\nA\n\n/* snapshot: third */\nD\n\n/* abide: end */\n"
+	if string(data) != expected {
+		t.Fatalf("Expected reviewed snapshots %q, instead got %q.", expected, data)
+	}
+
+	data, err = ioutil.ReadFile(path + ".new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "/* abide: v3 */\n\n/* snapshot: second */\nB\n\n/* abide: end */\n"
+	if string(data) != expected {
+		t.Fatalf("Expected the skipped change to remain pending %q, instead got %q.", expected, data)
+	}
+}
+
+func TestReviewQuit(t *testing.T) {
+	dir := testingDir(t)
+	path := filepath.Join(dir, "__snapshots__", "pkg.snapshot")
+
+	err := run([]string{"-dir", dir, "review"}, strings.NewReader("r\nq\n"), ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != testSnapshots {
+		t.Fatalf("Expected snapshots to be untouched, instead got %q.", data)
+	}
+
+	// reaching the end of stdin stops the review like quitting
+	err = run([]string{"-dir", dir, "review"}, strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	err = run([]string{"-dir", dir, "list"}, nil, &stdout, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "2 pending snapshot changes.") {
+		t.Fatalf("Expected the rejected change to be removed, instead got:\n%s", stdout.String())
+	}
+}
@@ -2,12 +2,127 @@ package abide
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
-	errUnableToLocateTestPath          = errors.New("unable to locate test path")
-	errUnableToCreateSnapshotDirectory = errors.New("unable to create snapshot directory")
-	errUnableToReadSnapshotDirectory   = errors.New("unable to read snapshot directory")
-	errUnableToLocateSnapshotByID      = errors.New("unable to locate snapshot by id")
-	errInvalidSnapshotID               = errors.New("invalid snapshot id")
+	// ErrUnableToLocateTestPath is returned when the directory of the package under test cannot be determined.
+	ErrUnableToLocateTestPath = errors.New("unable to locate test path")
+	// ErrUnableToCreateSnapshotDirectory is returned when SnapshotsDir cannot be created.
+	ErrUnableToCreateSnapshotDirectory = errors.New("unable to create snapshot directory")
+	// ErrUnableToReadSnapshotDirectory is returned when SnapshotsDir cannot be listed.
+	ErrUnableToReadSnapshotDirectory = errors.New("unable to read snapshot directory")
+	// ErrInvalidSnapshotID is returned when a snapshot id is malformed.
+	ErrInvalidSnapshotID = errors.New("invalid snapshot id")
+	// ErrDuplicateSnapshotID is returned when a snapshot id is defined in more than one
+	// snapshot file, or asserted with different values by more than one test.
+	ErrDuplicateSnapshotID = errors.New("duplicate snapshot id")
+	// ErrUnreadableSnapshotFile is returned when a snapshot file cannot be opened or read.
+	ErrUnreadableSnapshotFile = errors.New("unreadable snapshot file")
+	// ErrCorruptSnapshotFile is returned when a snapshot file is malformed or truncated.
+	ErrCorruptSnapshotFile = errors.New("corrupt snapshot file")
+	// ErrUnsupportedSnapshotFormat is returned when a snapshot file was written in an unknown format version.
+	ErrUnsupportedSnapshotFormat = errors.New("unsupported snapshot file format")
+	// ErrStrictMode is returned when a snapshot is missing, or would be created,
+	// updated or pruned, while strict mode is enabled.
+	ErrStrictMode = errors.New("snapshot writes are not allowed in strict mode")
+	// ErrObsoleteSnapshot is returned by Cleanup for snapshots no test asserted,
+	// when obsolete snapshots fail the run.
+	ErrObsoleteSnapshot = errors.New("obsolete snapshot")
 )
+
+// FileError describes a failure to load or save a snapshot file. Use
+// errors.Is to match the underlying sentinel error.
+type FileError struct {
+	// Path is the path of the snapshot file.
+	Path string
+	// Line is the line the error was found on, or 0 if the error
+	// does not relate to a specific line.
+	Line int
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *FileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// corruptErrorf returns an ErrCorruptSnapshotFile located at line.
+func corruptErrorf(line int, format string, args ...interface{}) error {
+	return &FileError{
+		Line: line,
+		Err:  fmt.Errorf("%w: %s", ErrCorruptSnapshotFile, fmt.Sprintf(format, args...)),
+	}
+}
+
+// withPath attaches path to err, which may already be a FileError
+// lacking a path.
+func withPath(path string, err error) error {
+	var fileErr *FileError
+	if errors.As(err, &fileErr) && fileErr.Path == "" {
+		fileErr.Path = path
+		return fileErr
+	}
+	return &FileError{Path: path, Err: err}
+}
+
+// multiError holds the errors of several snapshot files or snapshots.
+type multiError []error
+
+// joinErrors returns the errors of errs which are not nil as one error,
+// or nil if there are none.
+func joinErrors(errs ...error) error {
+	var m multiError
+	for _, err := range errs {
+		if err != nil {
+			m = append(m, err)
+		}
+	}
+
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	}
+	return m
+}
+
+// Error implements the error interface, with an error per line.
+func (m multiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is reports whether any of the errors matches target.
+func (m multiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching target.
+func (m multiError) As(target interface{}) bool {
+	for _, err := range m {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -11,9 +11,7 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	exit := m.Run()
-	abide.Cleanup()
-	os.Exit(exit)
+	os.Exit(abide.Run(m))
 }
 
 func TestRequests(t *testing.T) {
@@ -32,6 +32,11 @@ func ExampleString() {
 	abide.Assert(t, "assertable string", abide.String(myString))
 }
 
+func ExampleAssertAuto() {
+	// identified by the test name, e.g. "TestUsers/admin/1"
+	abide.AssertAuto(t, abide.String("this is a string I want to snapshot"))
+}
+
 func ExampleInterface() {
 	type MyStruct struct {
 		Field1 string
@@ -0,0 +1,167 @@
+package abide
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeSnapshotFile merges snaps into the snapshot file at path. The
+// file is re-read under a lock shared with other processes, so test
+// binaries writing to the same file never drop each other's snapshots;
+// only snapshots changed or removed by this process override its content.
+// The file is left untouched if its content does not change.
+func writeSnapshotFile(path string, snaps []*snapshot) error {
+	// pending files only exist while they hold changes to review
+	removeEmpty := isPendingPath(path)
+
+	err := modifySnapshotFile(path, removeEmpty, func(merged snapshots) {
+		for _, snap := range snaps {
+			switch {
+			case snap.shouldRemove:
+				delete(merged, snap.id)
+			case snap.dirty:
+				merged[snap.id] = snap
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range snaps {
+		snap.dirty = false
+	}
+
+	return nil
+}
+
+// modifySnapshotFile decodes the snapshot file at path, applies modify
+// and writes the result back, all while holding the lock of the file.
+// If removeEmpty is set, a file left without snapshots is removed.
+func modifySnapshotFile(path string, removeEmpty bool, modify func(snapshots)) error {
+	unlock, err := lockSnapshotFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// a file which cannot be read or decoded is never overwritten
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return &FileError{Path: path, Err: fmt.Errorf("%w: %v", ErrUnreadableSnapshotFile, err)}
+	}
+
+	merged, err := decode(existing)
+	if err != nil {
+		return withPath(path, err)
+	}
+
+	modify(merged)
+
+	if removeEmpty && len(merged) == 0 {
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := encode(merged)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(existing, data) {
+		return writeFileAtomic(path, data)
+	}
+
+	return nil
+}
+
+// lockSnapshotFile acquires an exclusive advisory lock guarding the
+// snapshot file at path across processes. The lock file lives in the
+// temporary directory to keep the snapshots directory clean.
+func lockSnapshotFile(path string) (unlock func(), err error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lockPath := filepath.Join(os.TempDir(), fmt.Sprintf("abide-%x.lock", sha1.Sum([]byte(abs))))
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of
+// path and renames it into place, so an interrupted write never leaves
+// a truncated file behind.
+func writeFileAtomic(path string, data []byte) (err error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir commits a rename within dir to stable storage. Directories
+// cannot be synced on every platform, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+
+	d.Sync()
+}
@@ -0,0 +1,117 @@
+package abide
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test"+snapshotExt)
+	err = ioutil.WriteFile(path, []byte("old"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writeFileAtomic(path, []byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Fatalf("Expected new, instead got %s.", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected mode 0600 to be preserved, instead got %v.", info.Mode().Perm())
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected temporary files to be removed, instead found %d files.", len(files))
+	}
+}
+
+// TestHelperProcessFlush is not a real test, it is executed as a
+// separate process by TestCrossProcessFlush.
+func TestHelperProcessFlush(t *testing.T) {
+	id := os.Getenv("ABIDE_HELPER_ID")
+	if id == "" {
+		return
+	}
+
+	SnapshotsDir = os.Getenv("ABIDE_HELPER_DIR")
+	_ = testingSnapshot(id, id)
+	err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCrossProcessFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	count := 8
+	cmds := []*exec.Cmd{}
+	for i := 0; i < count; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcessFlush$")
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("ABIDE_HELPER_ID=%d", i),
+			"ABIDE_HELPER_DIR="+dir,
+		)
+		err = cmd.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	for _, cmd := range cmds {
+		err = cmd.Wait()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkg, err := getTestingPackage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, pkg+snapshotExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snaps, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != count {
+		t.Fatalf("Expected %d snapshots, instead got %d.", count, len(snaps))
+	}
+}
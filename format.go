@@ -0,0 +1,223 @@
+package abide
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// formatVersion is the version of the snapshot file format written by encode.
+	formatVersion = 3
+	// formatPrefix starts the lines identifying the format of a snapshot file.
+	formatPrefix = "/* abide: "
+	// formatTrailer terminates a snapshot file, a missing trailer reveals truncation.
+	formatTrailer = formatPrefix + "end */"
+	// testPrefix starts the line naming the test owning a record, it
+	// directly follows the record header from version 3.
+	testPrefix = "/* test: "
+	// escapeChar is prepended to value lines which could be mistaken for
+	// record headers or format lines.
+	escapeChar = `\`
+)
+
+// formatHeader returns the first line of a snapshot file of the given version.
+func formatHeader(version int) string {
+	return fmt.Sprintf("%sv%d */", formatPrefix, version)
+}
+
+// decode decodes a slice of bytes to retrieve a Snapshots object. Files
+// starting with a format header are decoded by their version, others
+// are treated as legacy files predating the header.
+func decode(data []byte) (snapshots, error) {
+	if !bytes.HasPrefix(data, []byte(formatPrefix)) {
+		return decodeLegacy(data)
+	}
+
+	header := string(data)
+	if i := strings.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+
+	switch strings.TrimSuffix(header, "\r") {
+	case formatHeader(2):
+		return decodeVersion(data, 2)
+	case formatHeader(3):
+		return decodeVersion(data, 3)
+	default:
+		return nil, &FileError{Line: 1, Err: fmt.Errorf("%w: %q", ErrUnsupportedSnapshotFormat, header)}
+	}
+}
+
+// decodeVersion decodes a file of the following form, values are stored
+// byte-for-byte apart from escaping. The test line is optional, and only
+// part of version 3.
+//
+//	/* abide: v3 */
+//
+//	/* snapshot: id */
+//	/* test: TestName */
+//	value
+//
+//	/* abide: end */
+func decodeVersion(data []byte, version int) (snapshots, error) {
+	snaps := make(snapshots)
+
+	lines := strings.Split(string(data), "\n")
+	// files checked out with `\r\n` line endings end every line with `\r`
+	crlf := strings.HasSuffix(lines[0], "\r")
+	var current *snapshot
+	var values []string
+
+	// closeRecord stores the current record, its value is followed by
+	// a single blank line separating it from the next record.
+	closeRecord := func(line int) error {
+		if current == nil {
+			return nil
+		}
+		last := len(values) - 1
+		if last < 0 || strings.TrimSuffix(values[last], "\r") != "" {
+			return corruptErrorf(line+1, "missing blank line before record end")
+		}
+		current.value = strings.Join(values[:last], "\n")
+		if crlf {
+			// the last line ending belongs to the record, not the value
+			current.value = strings.TrimSuffix(current.value, "\r")
+		}
+		snaps[current.id] = current
+		current, values = nil, nil
+		return nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		structural := strings.TrimSuffix(line, "\r")
+
+		switch {
+		case strings.HasPrefix(structural, snapshotSeparator) && strings.HasSuffix(structural, " */"):
+			if err := closeRecord(i); err != nil {
+				return nil, err
+			}
+			id := snapshotID(strings.TrimSuffix(strings.TrimPrefix(structural, snapshotSeparator), " */"))
+			current, values = &snapshot{id: id}, nil
+			if version >= 3 && i+1 < len(lines) {
+				next := strings.TrimSuffix(lines[i+1], "\r")
+				if strings.HasPrefix(next, testPrefix) && strings.HasSuffix(next, " */") {
+					current.test = strings.TrimSuffix(strings.TrimPrefix(next, testPrefix), " */")
+					i++
+				}
+			}
+		case structural == formatTrailer:
+			if err := closeRecord(i); err != nil {
+				return nil, err
+			}
+			// only the final newline may follow the trailer
+			if i != len(lines)-2 || lines[i+1] != "" {
+				return nil, corruptErrorf(i+2, "unexpected content after trailer")
+			}
+			return snaps, nil
+		case current == nil:
+			if structural != "" {
+				return nil, corruptErrorf(i+1, "unexpected content outside of a record")
+			}
+		default:
+			values = append(values, unescapeLine(line, version))
+		}
+	}
+
+	return nil, corruptErrorf(len(lines), "missing %q trailer, the file may be truncated", formatTrailer)
+}
+
+// decodeLegacy decodes a file written before the format header was
+// introduced, where values are not escaped and surrounding whitespace
+// is not preserved.
+func decodeLegacy(data []byte) (snapshots, error) {
+	snaps := make(snapshots)
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && !bytes.HasPrefix(trimmed, []byte(snapshotSeparator)) {
+		return nil, corruptErrorf(1, "missing leading snapshot header")
+	}
+
+	line := 1
+	snapshotsStr := strings.Split(string(data), snapshotSeparator)
+	for _, s := range snapshotsStr {
+		start := line
+		line += strings.Count(s, "\n")
+		if s == "" {
+			continue
+		}
+
+		// a record cut short within its header was truncated
+		components := strings.SplitAfterN(s, "\n", 2)
+		header := strings.TrimSuffix(strings.TrimSuffix(components[0], "\n"), "\r")
+		if len(components) != 2 || !strings.HasSuffix(header, " */") {
+			return nil, corruptErrorf(start, "unterminated header %q", snapshotSeparator+header)
+		}
+
+		id := snapshotID(strings.TrimSuffix(header, " */"))
+		val := strings.TrimSpace(components[1])
+		snaps[id] = &snapshot{
+			id:    id,
+			value: val,
+		}
+	}
+
+	return snaps, nil
+}
+
+// encode encodes a snapshots object into a slice of bytes.
+func encode(snaps snapshots) ([]byte, error) {
+	var buf bytes.Buffer
+
+	ids := []string{}
+	for id := range snaps {
+		ids = append(ids, string(id))
+	}
+
+	sort.Strings(ids)
+
+	buf.WriteString(formatHeader(formatVersion) + "\n")
+	for _, id := range ids {
+		s := snaps[snapshotID(id)]
+
+		buf.WriteString(fmt.Sprintf("\n%s%s */\n", snapshotSeparator, string(s.id)))
+		if s.test != "" {
+			buf.WriteString(fmt.Sprintf("%s%s */\n", testPrefix, s.test))
+		}
+		for _, line := range strings.Split(s.value, "\n") {
+			buf.WriteString(escapeLine(line) + "\n")
+		}
+	}
+	buf.WriteString("\n" + formatTrailer + "\n")
+
+	return buf.Bytes(), nil
+}
+
+// escapeLine prepends escapeChar to a value line which, ignoring any
+// leading escapeChar, starts like a record header, test or format line.
+func escapeLine(line string) string {
+	if isEscapable(line, formatVersion) {
+		return escapeChar + line
+	}
+	return line
+}
+
+// unescapeLine reverses escapeLine for a file of the given version.
+func unescapeLine(line string, version int) string {
+	if strings.HasPrefix(line, escapeChar) && isEscapable(line[len(escapeChar):], version) {
+		return line[len(escapeChar):]
+	}
+	return line
+}
+
+// isEscapable reports whether line is escaped in a file of the given
+// version, test lines are only escaped from version 3.
+func isEscapable(line string, version int) bool {
+	line = strings.TrimLeft(line, escapeChar)
+	if version >= 3 && strings.HasPrefix(line, testPrefix) {
+		return true
+	}
+	return strings.HasPrefix(line, snapshotSeparator) || strings.HasPrefix(line, formatPrefix)
+}
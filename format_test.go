@@ -0,0 +1,137 @@
+package abide
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	s := snapshots{
+		"1": &snapshot{id: "1", value: "A"},
+		"2": &snapshot{id: "2", value: "/* snapshot: 1 */\nB"},
+		"3": &snapshot{id: "3", value: "\\/* snapshot: 1 */\n\\\\/* abide: end */"},
+		"4": &snapshot{id: "4", value: "/* abide: v2 */\n\n"},
+		"5": &snapshot{id: "5", value: ""},
+		"6": &snapshot{id: "6", value: "/* test: TestB */\nC", test: "TestA"},
+		"7": &snapshot{id: "7", value: "/* test: TestB */"},
+	}
+
+	data, err := encode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(s, decoded) {
+		t.Fatalf("Failed to round-trip snapshots, got %s.", data)
+	}
+}
+
+func TestDecodeV2(t *testing.T) {
+	// version 2 did not escape test lines
+	data := []byte("/* abide: v2 */\n\n/* snapshot: 1 */\n/* test: TestA */\n\\/* test: TestB */\n\n/* abide: end */\n")
+
+	snaps, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := snapshots{
+		"1": &snapshot{id: "1", value: "/* test: TestA */\n\\/* test: TestB */"},
+	}
+	if !reflect.DeepEqual(expected, snaps) {
+		t.Fatalf("Failed to decode version 2 snapshots, got %q.", snaps["1"].value)
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	data := []byte("/* snapshot: 1 */\nA\n\n/* snapshot: 2 */\n  B\n")
+
+	snaps, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := snapshots{
+		"1": &snapshot{id: "1", value: "A"},
+		"2": &snapshot{id: "2", value: "B"},
+	}
+	if !reflect.DeepEqual(expected, snaps) {
+		t.Fatal("Failed to decode legacy snapshots.")
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	data, err := encode(snapshots{
+		"1": &snapshot{id: "1", value: "A"},
+		"2": &snapshot{id: "2", value: "B"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := []byte("/* snapshot: 1 */\nA\n\n/* snapshot: 2 */\nB")
+
+	cases := map[string][]byte{
+		"truncated":                 data[:len(data)-5],
+		"truncated value":           data[:bytes.LastIndex(data, []byte("B"))],
+		"trailing content":          append(append([]byte{}, data...), "C\n"...),
+		"legacy truncated header":   legacy[:bytes.LastIndex(legacy, []byte(" */"))],
+		"legacy leading content":    append([]byte("garbage\n"), legacy...),
+		"missing record separation": bytes.Replace(data, []byte("A\n\n"), []byte("A\n"), 1),
+	}
+
+	for name, c := range cases {
+		_, err = decode(c)
+		if !errors.Is(err, ErrCorruptSnapshotFile) {
+			t.Errorf("%s: Expected ErrCorruptSnapshotFile, instead got %v.", name, err)
+		}
+	}
+
+	_, err = decode([]byte("/* abide: v99 */\n"))
+	if !errors.Is(err, ErrUnsupportedSnapshotFormat) {
+		t.Fatalf("Expected ErrUnsupportedSnapshotFormat, instead got %v.", err)
+	}
+}
+
+func benchmarkEncode(count int, b *testing.B) {
+	defer testingCleanup()
+	s := testingSnapshots(count)
+	for i := 0; i < b.N; i++ {
+		encode(s)
+	}
+}
+
+func BenchmarkEncode10(b *testing.B)   { benchmarkEncode(10, b) }
+func BenchmarkEncode100(b *testing.B)  { benchmarkEncode(100, b) }
+func BenchmarkEncode1000(b *testing.B) { benchmarkEncode(1000, b) }
+
+func TestDecodeCRLF(t *testing.T) {
+	data, err := encode(snapshots{
+		"1": &snapshot{id: "1", value: "A"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"v2":     bytes.Replace(data, []byte("\n"), []byte("\r\n"), -1),
+		"legacy": []byte("/* snapshot: 1 */\r\nA\r\n"),
+	}
+
+	for name, f := range files {
+		snaps, err := decode(f)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if snaps["1"] == nil || normalizeLineEndings(snaps["1"].value) != "A" {
+			t.Fatalf("%s: Failed to decode snapshot with \\r\\n line endings.", name)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package abide
+
+import (
+	"strings"
+)
+
+// LineEnding determines how line endings are normalized before a value
+// is compared to, or stored as, a snapshot.
+type LineEnding int
+
+const (
+	// LineEndingLF converts `\r\n` line endings to `\n`.
+	LineEndingLF LineEnding = iota
+	// LineEndingPreserve leaves line endings untouched.
+	LineEndingPreserve
+	// LineEndingCRLF converts `\n` line endings to `\r\n`.
+	LineEndingCRLF
+)
+
+// LineEndings is the line ending normalization applied to both the stored
+// and the actual value of an assertion. Values asserted with Exact, or while
+// ExactWhitespace is set, are never normalized.
+var LineEndings = LineEndingLF
+
+// normalizeLineEndings applies LineEndings to s.
+func normalizeLineEndings(s string) string {
+	switch LineEndings {
+	case LineEndingPreserve:
+		return s
+	case LineEndingCRLF:
+		s = strings.Replace(s, "\r\n", "\n", -1)
+		return strings.Replace(s, "\n", "\r\n", -1)
+	default:
+		return strings.Replace(s, "\r\n", "\n", -1)
+	}
+}
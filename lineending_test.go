@@ -0,0 +1,46 @@
+package abide
+
+import (
+	"testing"
+)
+
+func TestNormalizeLineEndings(t *testing.T) {
+	defer func(l LineEnding) { LineEndings = l }(LineEndings)
+
+	cases := map[LineEnding]string{
+		LineEndingLF:       "a\nb\nc\n",
+		LineEndingPreserve: "a\r\nb\nc\r\n",
+		LineEndingCRLF:     "a\r\nb\r\nc\r\n",
+	}
+
+	for l, expected := range cases {
+		LineEndings = l
+		result := normalizeLineEndings("a\r\nb\nc\r\n")
+		if result != expected {
+			t.Errorf("normalizeLineEndings(%d) unexpected result. Got=%q, Want=%q", l, result, expected)
+		}
+	}
+}
+
+func TestAssertLineEndings(t *testing.T) {
+	defer testingCleanup()
+	defer func(l LineEnding) { LineEndings = l }(LineEndings)
+
+	// as if checked out with `\r\n` conversion
+	_ = testingSnapshot("crlf", "a\r\nb")
+
+	withArguments(updateNone, true, func() {
+		t2 := &testing.T{}
+		Assert(t2, "crlf", String("a\nb"))
+		if t2.Failed() {
+			t.Fatal("Expected line endings to be normalized.")
+		}
+
+		LineEndings = LineEndingPreserve
+		t3 := &testing.T{}
+		Assert(t3, "crlf", String("a\nb"))
+		if !t3.Failed() {
+			t.Fatal("Expected preserved line endings to differ.")
+		}
+	})
+}
@@ -0,0 +1,18 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package abide
+
+import (
+	"os"
+)
+
+// lockFile is a no-op on platforms without advisory file locks.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without advisory file locks.
+func unlockFile(f *os.File) error {
+	return nil
+}
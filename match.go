@@ -0,0 +1,209 @@
+package abide
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Status is the outcome of comparing a value to its snapshot.
+type Status int
+
+const (
+	// StatusNew indicates no snapshot exists for the value, and none was created.
+	StatusNew Status = iota
+	// StatusCreated indicates a snapshot was created for the value.
+	StatusCreated
+	// StatusMatched indicates the value matches its snapshot.
+	StatusMatched
+	// StatusMismatched indicates the value does not match its snapshot.
+	StatusMismatched
+	// StatusUpdated indicates the snapshot was updated to the value.
+	StatusUpdated
+)
+
+// String returns the name of the status.
+func (s Status) String() string {
+	switch s {
+	case StatusNew:
+		return "new"
+	case StatusCreated:
+		return "created"
+	case StatusMatched:
+		return "matched"
+	case StatusMismatched:
+		return "mismatched"
+	case StatusUpdated:
+		return "updated"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the status as its name.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the name of a status.
+func (s *Status) UnmarshalText(text []byte) error {
+	for status := StatusNew; status <= StatusUpdated; status++ {
+		if status.String() == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %q", text)
+}
+
+// Result describes the outcome of comparing a value to its snapshot.
+type Result struct {
+	// ID identifies the snapshot.
+	ID string `json:"id"`
+	// Test is the name of the test which asserted the value, empty for Match.
+	Test string `json:"test,omitempty"`
+	// Path is the path of the snapshot file, empty if the snapshot does
+	// not exist and was not created.
+	Path string `json:"path,omitempty"`
+	// Status is the outcome of the comparison.
+	Status Status `json:"status"`
+	// Stored is the value of the existing snapshot, empty if there was none.
+	Stored string `json:"stored"`
+	// Actual is the value compared to the snapshot.
+	Actual string `json:"actual"`
+	// Diff is a diff of Stored and Actual without colors, marking deletions
+	// as [-text-] and insertions as {+text+}, empty if they match.
+	Diff string `json:"diff,omitempty"`
+	// Pending reports whether Actual was written to a pending snapshot
+	// file for review, see PendingChanges.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Failed reports whether the result should fail a test, i.e. the
+// snapshot is missing or does not match.
+func (r Result) Failed() bool {
+	return r.Status == StatusNew || r.Status == StatusMismatched
+}
+
+// Match compares the value of a to the snapshot identified by id, creating
+// or updating the snapshot when requested, exactly like Assert. Instead of
+// reporting to a test, it returns the result, allowing any test framework
+// to build its own reporting on top of the snapshot store. Snapshots written
+// by Match are persisted by Flush or Cleanup.
+func Match(id string, a Assertable) (Result, error) {
+	_, exact := a.(exactAssertable)
+	return match(snapshotID(id), a.String(), exact, "")
+}
+
+// match compares data to the snapshot identified by id on behalf of test,
+// see compareSnapshot, and records the result for the summary of the run.
+func match(id snapshotID, data string, exact bool, test string) (Result, error) {
+	result, err := compareSnapshot(id, data, exact, test)
+	if err == nil || errors.Is(err, ErrStrictMode) {
+		recordResult(result)
+	}
+	return result, err
+}
+
+// compareSnapshot compares data to the snapshot identified by id on
+// behalf of test, creating or updating the snapshot when requested. In
+// review mode, new and mismatched values are written to pending files
+// instead. In strict mode, a missing snapshot or a requested update is an
+// ErrStrictMode. Unless exact or ExactWhitespace is set, surrounding
+// whitespace is ignored and line endings are normalized according to
+// LineEndings.
+func compareSnapshot(id snapshotID, data string, exact bool, test string) (Result, error) {
+	result := Result{ID: string(id), Test: test}
+	if err := loadArguments(); err != nil {
+		return result, err
+	}
+	if !id.isValid() {
+		return result, fmt.Errorf("%w: %q", ErrInvalidSnapshotID, id)
+	}
+
+	snapshot, err := evaluateSnapshot(id)
+	if err != nil {
+		return result, err
+	}
+
+	exact = exact || ExactWhitespace
+	if !exact {
+		data = normalizeLineEndings(strings.TrimSpace(data))
+	}
+	result.Actual = data
+
+	err = recordAssertion(id, test, data)
+	if err != nil {
+		return result, err
+	}
+
+	canUpdate := args.canUpdate(id, test)
+	review := args.update == updateReview && canUpdate
+	if snapshot == nil {
+		if args.strict {
+			result.Status = StatusNew
+			return result, fmt.Errorf("%w: %q has no snapshot, create it locally and commit it", ErrStrictMode, id)
+		}
+		if review {
+			result.Status = StatusNew
+			result.Pending = true
+			return result, writePendingSnapshot(id, data, test, "")
+		}
+		if !args.update.shouldCreate() || !canUpdate {
+			result.Status = StatusNew
+			return result, nil
+		}
+
+		fmt.Printf("Creating snapshot `%s`\n", id)
+		s, err := writeSnapshot(id, data, test, true)
+		if err != nil {
+			return result, err
+		}
+		result.Path = s.path
+		result.Status = StatusCreated
+		return result, nil
+	}
+
+	result.Path = snapshot.path
+	result.Stored = snapshot.value
+	if !exact {
+		result.Stored = normalizeLineEndings(result.Stored)
+	}
+
+	result.Diff = plainDiff(result.Stored, data)
+	if result.Diff == "" {
+		if review {
+			discardPendingSnapshot(id, snapshot.path)
+		}
+		// record the owner of snapshots written before owners were
+		// tracked, or since asserted by a renamed test
+		if test != "" && canUpdate && !args.strict && (args.update.shouldCreate() || args.update.shouldUpdate()) {
+			claimSnapshot(id, test)
+		}
+		result.Status = StatusMatched
+		return result, nil
+	}
+
+	if (args.update.shouldUpdate() && canUpdate) || review {
+		if args.strict {
+			result.Status = StatusMismatched
+			return result, fmt.Errorf("%w: refusing to update %q\n%s", ErrStrictMode, id, result.Diff)
+		}
+		if review {
+			result.Status = StatusMismatched
+			result.Pending = true
+			return result, writePendingSnapshot(id, data, test, snapshot.path)
+		}
+		fmt.Printf("Updating snapshot `%s`\n", id)
+		s, err := updateSnapshot(id, data, test)
+		if err != nil {
+			return result, err
+		}
+		result.Path = s.path
+		result.Status = StatusUpdated
+		return result, nil
+	}
+
+	result.Status = StatusMismatched
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package abide
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("existing", "A")
+
+	cases := []struct {
+		id     string
+		value  string
+		update updateMode
+		status Status
+	}{
+		{"existing", "A", updateNone, StatusMatched},
+		{"existing", "B", updateNone, StatusMismatched},
+		{"existing", "B", updateNew, StatusMismatched},
+		{"existing", "B", updateMismatched, StatusUpdated},
+		{"existing", "C", updateAll, StatusUpdated},
+		{"missing", "C", updateNone, StatusNew},
+		{"missing", "C", updateMismatched, StatusNew},
+		{"missing", "C", updateNew, StatusCreated},
+		{"missing", "D", updateAll, StatusUpdated},
+		{"missing", "D", updateNone, StatusMatched},
+	}
+
+	for _, c := range cases {
+		withArguments(c.update, true, func() {
+			result, err := Match(c.id, String(c.value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != c.status {
+				t.Fatalf("Expected %s for %s=%s, instead got %s.", c.status, c.id, c.value, result.Status)
+			}
+			if result.Actual != c.value {
+				t.Fatalf("Expected actual value %s, instead got %s.", c.value, result.Actual)
+			}
+			if (result.Diff != "") != (result.Status == StatusMismatched || result.Status == StatusUpdated) {
+				t.Fatalf("Unexpected diff %q for %s.", result.Diff, result.Status)
+			}
+		})
+	}
+
+	result, err := Match("existing", String("C"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stored != "C" || result.Failed() {
+		t.Fatalf("Expected updated snapshot to match, instead got %+v.", result)
+	}
+
+	_, err = Match("", String("A"))
+	if !errors.Is(err, ErrInvalidSnapshotID) {
+		t.Fatalf("Expected ErrInvalidSnapshotID, instead got %v.", err)
+	}
+}
+
+func TestMatchOwner(t *testing.T) {
+	defer testingCleanup()
+
+	_, err := writeSnapshot("owned", "A", "TestOwner", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withArguments(updateAll, true, func() {
+		_, err = Match("owned", String("B"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := getSnapshot("owned"); s == nil || s.value != "B" || s.test != "TestOwner" {
+		t.Fatalf("Expected the updated snapshot to keep its owner, instead got %+v.", s)
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("users", "A")
+	_ = testingSnapshot("posts", "A")
+
+	withArguments(updateAll, true, func() {
+		args.filter = regexp.MustCompile("^users$")
+		defer func() { args.filter = nil }()
+
+		result, err := Match("users", String("B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != StatusUpdated {
+			t.Fatalf("Expected %s, instead got %s.", StatusUpdated, result.Status)
+		}
+
+		result, err = Match("posts", String("B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != StatusMismatched {
+			t.Fatalf("Expected %s, instead got %s.", StatusMismatched, result.Status)
+		}
+
+		result, err = Match("comments", String("C"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != StatusNew {
+			t.Fatalf("Expected %s, instead got %s.", StatusNew, result.Status)
+		}
+	})
+}
+
+func TestMatchStrict(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("existing", "A")
+
+	withArguments(updateAll, true, func() {
+		args.strict = true
+
+		result, err := Match("existing", String("A"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != StatusMatched {
+			t.Fatalf("Expected %s, instead got %s.", StatusMatched, result.Status)
+		}
+
+		result, err = Match("existing", String("B"))
+		if !errors.Is(err, ErrStrictMode) {
+			t.Fatalf("Expected ErrStrictMode, instead got %v.", err)
+		}
+		if result.Status != StatusMismatched {
+			t.Fatalf("Expected %s, instead got %s.", StatusMismatched, result.Status)
+		}
+
+		result, err = Match("missing", String("C"))
+		if !errors.Is(err, ErrStrictMode) {
+			t.Fatalf("Expected ErrStrictMode, instead got %v.", err)
+		}
+		if result.Status != StatusNew {
+			t.Fatalf("Expected %s, instead got %s.", StatusNew, result.Status)
+		}
+	})
+
+	if s := getSnapshot("existing"); s.value != "A" {
+		t.Fatalf("Expected snapshot to remain A, instead got %s.", s.value)
+	}
+	if s := getSnapshot("missing"); s != nil {
+		t.Fatal("Expected no snapshot to be created.")
+	}
+}
@@ -0,0 +1,137 @@
+package abide
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// SnapshotMatcher is a Gomega compatible matcher comparing the actual
+// value to a snapshot. It implements the Match, FailureMessage and
+// NegatedFailureMessage methods of Gomega's types.GomegaMatcher.
+type SnapshotMatcher struct {
+	id     string
+	result Result
+}
+
+// MatchSnapshot returns a matcher comparing the actual value to the snapshot
+// identified by id, creating or updating the snapshot when requested.
+//
+//	Expect(body).To(abide.MatchSnapshot("users endpoint"))
+//
+// Snapshots written by the matcher are persisted by Flush or Cleanup.
+func MatchSnapshot(id string) *SnapshotMatcher {
+	return &SnapshotMatcher{id: id}
+}
+
+// Match compares actual to the snapshot.
+func (m *SnapshotMatcher) Match(actual interface{}) (bool, error) {
+	a, err := toAssertable(actual)
+	if err != nil {
+		return false, err
+	}
+
+	m.result, err = Match(m.id, a)
+	if err != nil {
+		return false, err
+	}
+
+	return !m.result.Failed(), nil
+}
+
+// FailureMessage describes why actual did not match the snapshot.
+func (m *SnapshotMatcher) FailureMessage(actual interface{}) string {
+	return failureMessage(m.result)
+}
+
+// NegatedFailureMessage describes why actual unexpectedly matched the snapshot.
+func (m *SnapshotMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected value not to match snapshot %q", m.id)
+}
+
+// ErrorfT is the subset of testify's assert.TestingT used by AssertSnapshot.
+type ErrorfT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertSnapshot compares actual to the snapshot identified by id in the style
+// of testify's assert package, returning whether it matched. Optional
+// msgAndArgs are included in the failure message.
+//
+//	abide.AssertSnapshot(s.T(), "users endpoint", body)
+func AssertSnapshot(t ErrorfT, id string, actual interface{}, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	a, err := toAssertable(actual)
+	if err != nil {
+		t.Errorf("%v%s", err, formatMsgAndArgs(msgAndArgs))
+		return false
+	}
+
+	name := ""
+	if n, ok := t.(interface{ Name() string }); ok {
+		name = n.Name()
+	}
+	// like Assert, track the completion of a *testing.T for pruning
+	if tt, ok := t.(TestingT); ok {
+		trackCompletion(tt)
+	}
+
+	_, exact := a.(exactAssertable)
+	result, err := match(snapshotID(id), a.String(), exact, name)
+	if err != nil {
+		t.Errorf("%v%s", err, formatMsgAndArgs(msgAndArgs))
+		return false
+	}
+
+	if result.Failed() {
+		t.Errorf("%s%s", failureMessage(result), formatMsgAndArgs(msgAndArgs))
+		return false
+	}
+	return true
+}
+
+// toAssertable converts a value handed to a matcher to an Assertable.
+func toAssertable(actual interface{}) (Assertable, error) {
+	switch v := actual.(type) {
+	case nil:
+		return nil, fmt.Errorf("cannot snapshot a nil value")
+	case Assertable:
+		return v, nil
+	case string:
+		return String(v), nil
+	case []byte:
+		return String(string(v)), nil
+	case io.Reader:
+		data, err := ioutil.ReadAll(v)
+		if err != nil {
+			return nil, err
+		}
+		return String(string(data)), nil
+	default:
+		return Interface(v), nil
+	}
+}
+
+// failureMessage describes a failed result.
+func failureMessage(r Result) string {
+	if r.Status == StatusNew {
+		return newSnapshotMessage(r.ID, r.Actual, r.Pending)
+	}
+	return didNotMatchMessage(r.ID, compareResults(r.Stored, r.Actual), r.Pending)
+}
+
+// formatMsgAndArgs formats testify style msgAndArgs.
+func formatMsgAndArgs(msgAndArgs []interface{}) string {
+	if len(msgAndArgs) == 0 {
+		return ""
+	}
+
+	msg := fmt.Sprint(msgAndArgs[0])
+	if format, ok := msgAndArgs[0].(string); ok && len(msgAndArgs) > 1 {
+		msg = fmt.Sprintf(format, msgAndArgs[1:]...)
+	}
+	return "\nMessages: " + msg
+}
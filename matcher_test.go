@@ -0,0 +1,88 @@
+package abide
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// errorfT is a testify style TestingT.
+type errorfT struct {
+	errors []string
+}
+
+func (e *errorfT) Errorf(format string, args ...interface{}) {
+	e.errors = append(e.errors, fmt.Sprintf(format, args...))
+}
+
+func TestSnapshotMatcher(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("matcher", "A")
+
+	withArguments(updateNone, true, func() {
+		m := MatchSnapshot("matcher")
+		ok, err := m.Match([]byte("A"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("Expected value to match.")
+		}
+
+		ok, err = m.Match(strings.NewReader("B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Fatal("Expected value not to match.")
+		}
+		if !strings.Contains(m.FailureMessage("B"), "does not match") {
+			t.Fatalf("Unexpected failure message %q.", m.FailureMessage("B"))
+		}
+
+		_, err = MatchSnapshot("matcher").Match(nil)
+		if err == nil {
+			t.Fatal("Expected an error for nil.")
+		}
+	})
+
+	withArguments(updateAll, true, func() {
+		m := MatchSnapshot("matcher")
+		ok, err := m.Match("B")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("Expected value to be updated.")
+		}
+	})
+}
+
+func TestAssertSnapshot(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("testify", "A")
+
+	withArguments(updateNone, true, func() {
+		e := &errorfT{}
+		if !AssertSnapshot(e, "testify", "A") {
+			t.Fatalf("Expected value to match, instead got %v.", e.errors)
+		}
+
+		if AssertSnapshot(e, "testify", "B", "request %d", 1) {
+			t.Fatal("Expected value not to match.")
+		}
+		if len(e.errors) != 1 || !strings.Contains(e.errors[0], "Messages: request 1") {
+			t.Fatalf("Unexpected errors %v.", e.errors)
+		}
+	})
+
+	// the completion of a *testing.T is tracked for pruning
+	withArguments(updateAll, true, func() {
+		t.Run("testify", func(t *testing.T) {
+			AssertSnapshot(t, "testify tracked", "B")
+		})
+	})
+	if !tests[t.Name()+"/testify"] {
+		t.Fatalf("Expected the completed test to be tracked, instead got %v.", tests)
+	}
+}
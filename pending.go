@@ -0,0 +1,228 @@
+package abide
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pendingExt is appended to the path of a snapshot file to name the file
+// holding the changes to it which await review.
+const pendingExt = ".new"
+
+// isPendingPath reports whether path names a pending snapshot file.
+func isPendingPath(path string) bool {
+	return strings.HasSuffix(path, snapshotExt+pendingExt)
+}
+
+// writePendingSnapshot writes value, asserted by test, to the pending file
+// of the snapshot file at path, or of the package under test if path is
+// empty. The change is persisted by Flush or Cleanup.
+func writePendingSnapshot(id snapshotID, value, test, path string) error {
+	if path == "" {
+		var err error
+		path, err = getSnapshotPath()
+		if err != nil {
+			return err
+		}
+	}
+	path += pendingExt
+
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	pendingSnapshots[id] = &snapshot{
+		id:    id,
+		value: value,
+		test:  test,
+		path:  path,
+		dirty: true,
+	}
+	dirtyPaths[path] = true
+	return nil
+}
+
+// discardPendingSnapshot removes a stale pending change of the snapshot
+// file at path, once the value matches its snapshot again.
+func discardPendingSnapshot(id snapshotID, path string) {
+	path += pendingExt
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	pendingSnapshots[id] = &snapshot{
+		id:           id,
+		path:         path,
+		shouldRemove: true,
+	}
+	dirtyPaths[path] = true
+}
+
+// PendingChange is a snapshot value written by a run in review mode,
+// awaiting to be accepted into, or rejected from, its snapshot file.
+type PendingChange struct {
+	// ID identifies the snapshot.
+	ID string
+	// Path is the path of the snapshot file the change is accepted into.
+	Path string
+	// Stored is the value of the existing snapshot, empty if there is none.
+	Stored string
+	// Pending is the value awaiting review.
+	Pending string
+	// Test is the name of the test which asserted Pending, empty if unknown.
+	Test string
+	// New reports whether the snapshot does not exist yet.
+	New bool
+	// Diff is a diff of Stored and Pending without colors, marking deletions
+	// as [-text-] and insertions as {+text+}.
+	Diff string
+}
+
+// PendingChanges returns the pending changes of the snapshot files in
+// dir and its subdirectories, ordered by path and id. Hidden directories
+// are skipped.
+func PendingChanges(dir string) ([]PendingChange, error) {
+	changes := []PendingChange{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isPendingPath(path) {
+			return nil
+		}
+
+		c, err := readPendingChanges(strings.TrimSuffix(path, pendingExt))
+		if err != nil {
+			return err
+		}
+		changes = append(changes, c...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// readPendingChanges returns the pending changes of the snapshot file at
+// path, ordered by id.
+func readPendingChanges(path string) ([]PendingChange, error) {
+	pending, err := readSnapshotFile(path + pendingExt)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := readSnapshotFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+
+	changes := make([]PendingChange, 0, len(ids))
+	for _, id := range ids {
+		change := PendingChange{
+			ID:      id,
+			Path:    path,
+			Pending: pending[snapshotID(id)].value,
+			Test:    pending[snapshotID(id)].test,
+		}
+		if s, ok := stored[snapshotID(id)]; ok {
+			change.Stored = s.value
+		} else {
+			change.New = true
+		}
+		change.Diff = plainDiff(change.Stored, change.Pending)
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// readSnapshotFile decodes the snapshot file at path, a missing file
+// holds no snapshots.
+func readSnapshotFile(path string) (snapshots, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, &FileError{Path: path, Err: fmt.Errorf("%w: %v", ErrUnreadableSnapshotFile, err)}
+	}
+
+	snaps, err := decode(data)
+	if err != nil {
+		return nil, withPath(path, err)
+	}
+	return snaps, nil
+}
+
+// AcceptPending writes the pending values of changes to their snapshot
+// files, and removes them from the pending files.
+func AcceptPending(changes ...PendingChange) error {
+	return resolvePending(changes, true)
+}
+
+// RejectPending removes changes from the pending files, leaving their
+// snapshot files untouched.
+func RejectPending(changes ...PendingChange) error {
+	return resolvePending(changes, false)
+}
+
+// resolvePending removes changes from the pending files, after merging
+// them into their snapshot files if accept is set.
+func resolvePending(changes []PendingChange, accept bool) error {
+	byPath := map[string][]PendingChange{}
+	paths := []string{}
+	for _, c := range changes {
+		if _, ok := byPath[c.Path]; !ok {
+			paths = append(paths, c.Path)
+		}
+		byPath[c.Path] = append(byPath[c.Path], c)
+	}
+
+	for _, path := range paths {
+		changes := byPath[path]
+
+		if accept {
+			err := modifySnapshotFile(path, false, func(snaps snapshots) {
+				for _, c := range changes {
+					id := snapshotID(c.ID)
+					s := &snapshot{id: id, value: c.Pending, test: c.Test}
+					if prev, ok := snaps[id]; ok && s.test == "" {
+						s.test = prev.test
+					}
+					snaps[id] = s
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err := modifySnapshotFile(path+pendingExt, true, func(snaps snapshots) {
+			for _, c := range changes {
+				delete(snaps, snapshotID(c.ID))
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package abide
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReviewPending(t *testing.T) {
+	defer testingCleanup()
+	existing := testingSnapshot("existing", "A")
+	err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withArguments(updateReview, true, func() {
+		for id, value := range map[string]string{"existing": "B", "missing": "C"} {
+			result, err := Match(id, String(value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Failed() || !result.Pending {
+				t.Fatalf("Expected a failed, pending result for %s, instead got %+v.", id, result)
+			}
+		}
+	})
+
+	err = Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := findOrCreateSnapshotDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := PendingChanges(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 pending changes, instead got %d.", len(changes))
+	}
+	if c := changes[0]; c.ID != "existing" || c.New || c.Stored != "A" || c.Pending != "B" || c.Path != existing.path ||
+		c.Diff != "[-A-]{+B+}" {
+		t.Fatalf("Unexpected pending change %+v.", c)
+	}
+	if c := changes[1]; c.ID != "missing" || !c.New || c.Pending != "C" {
+		t.Fatalf("Unexpected pending change %+v.", c)
+	}
+
+	// the snapshot file is untouched until changes are accepted
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := getSnapshot("existing"); s.value != "A" {
+		t.Fatalf("Expected snapshot to remain A, instead got %s.", s.value)
+	}
+
+	err = AcceptPending(changes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RejectPending(changes[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := getSnapshot("existing"); s.value != "B" {
+		t.Fatalf("Expected accepted snapshot B, instead got %s.", s.value)
+	}
+	if s := getSnapshot("missing"); s != nil {
+		t.Fatal("Expected rejected snapshot not to be created.")
+	}
+	if _, err := os.Stat(existing.path + pendingExt); !os.IsNotExist(err) {
+		t.Fatalf("Expected the resolved pending file to be removed, instead got %v.", err)
+	}
+}
+
+func TestReviewDiscardsStalePending(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("existing", "A")
+
+	withArguments(updateReview, true, func() {
+		_, err := Match("existing", String("B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// asserting the stored value again discards the pending change
+		assertions = map[snapshotID]assertion{}
+		_, err = Match("existing", String("A"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	changes, err := PendingChanges(SnapshotsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Expected no pending changes, instead got %+v.", changes)
+	}
+}
@@ -0,0 +1,199 @@
+package abide
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Reporter reports the results of the snapshots asserted by a package.
+type Reporter interface {
+	// Report is called by Cleanup with the name of the package under
+	// test and its results, ordered by snapshot id.
+	Report(pkg string, results []Result) error
+}
+
+// Reporters are called by Cleanup, in addition to the reporters writing
+// the files requested by -abide.results, -abide.junit and -abide.tap.
+var Reporters []Reporter
+
+// sortedResults returns the results of the run, ordered by snapshot id.
+// The caller must hold allSnapMutex.
+func sortedResults() []Result {
+	sorted := make([]Result, 0, len(results))
+	for _, r := range results {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// report calls Reporters with the sorted results of pkg and writes the
+// requested report files. The caller must not hold allSnapMutex, as
+// reporters may call Flush or Match.
+func report(pkg string, sorted []Result) error {
+	for _, r := range Reporters {
+		if err := r.Report(pkg, sorted); err != nil {
+			return err
+		}
+	}
+
+	files := []struct {
+		path        string
+		newReporter func(io.Writer) Reporter
+	}{
+		{args.resultsPath, NewJSONReporter},
+		{args.junitPath, NewJUnitReporter},
+		{args.tapPath, NewTAPReporter},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		if err := reportToFile(f.path, f.newReporter, pkg, sorted); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportToFile writes the report of the Reporter returned by newReporter
+// to path, relative paths are resolved to the package directory.
+func reportToFile(path string, newReporter func(io.Writer) Reporter, pkg string, results []Result) error {
+	if !filepath.IsAbs(path) {
+		dir, err := getTestingPath()
+		if err != nil {
+			return ErrUnableToLocateTestPath
+		}
+		path = filepath.Join(dir, path)
+	}
+
+	var buf bytes.Buffer
+	if err := newReporter(&buf).Report(pkg, results); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// failureDetail returns the message and body describing a failed result.
+func failureDetail(r Result) (message, body string) {
+	if r.Status == StatusNew {
+		return "no snapshot exists", r.Actual
+	}
+	return "existing snapshot does not match", plainDiff(r.Stored, r.Actual)
+}
+
+// junitReporter writes results as JUnit XML.
+type junitReporter struct {
+	w io.Writer
+}
+
+// NewJUnitReporter returns a Reporter writing the results of a package to
+// w as JUnit XML, with a test case per snapshot id.
+func NewJUnitReporter(w io.Writer) Reporter {
+	return &junitReporter{w: w}
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// Report implements Reporter.
+func (r *junitReporter) Report(pkg string, results []Result) error {
+	suite := junitSuite{Name: pkg, Tests: len(results), Cases: []junitCase{}}
+	for _, res := range results {
+		c := junitCase{Name: res.ID, Classname: pkg}
+		if res.Test != "" {
+			c.Classname = pkg + "." + res.Test
+		}
+		if res.Failed() {
+			message, body := failureDetail(res)
+			c.Failure = &junitFailure{Message: message, Type: res.Status.String(), Body: body}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	data, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(r.w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// tapReporter writes results in the Test Anything Protocol.
+type tapReporter struct {
+	w io.Writer
+}
+
+// NewTAPReporter returns a Reporter writing the results of a package to w
+// in the Test Anything Protocol version 13, with a test point per snapshot
+// id. Failures are described by a YAML block.
+func NewTAPReporter(w io.Writer) Reporter {
+	return &tapReporter{w: w}
+}
+
+// Report implements Reporter.
+func (r *tapReporter) Report(pkg string, results []Result) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(results))
+
+	for i, res := range results {
+		// a # would start a directive
+		description := strings.ReplaceAll(pkg+": "+res.ID, "#", `\#`)
+		if !res.Failed() {
+			fmt.Fprintf(&b, "ok %d - %s (%s)\n", i+1, description, res.Status)
+			continue
+		}
+
+		message, body := failureDetail(res)
+		fmt.Fprintf(&b, "not ok %d - %s (%s)\n", i+1, description, res.Status)
+		b.WriteString("  ---\n")
+		fmt.Fprintf(&b, "  message: %q\n", message)
+		if res.Test != "" {
+			fmt.Fprintf(&b, "  test: %q\n", res.Test)
+		}
+		key := "diff"
+		if res.Status == StatusNew {
+			key = "actual"
+		}
+		fmt.Fprintf(&b, "  %s: |-\n", key)
+		for _, line := range strings.Split(body, "\n") {
+			b.WriteString("    " + line + "\n")
+		}
+		b.WriteString("  ...\n")
+	}
+
+	_, err := io.WriteString(r.w, b.String())
+	return err
+}
@@ -0,0 +1,139 @@
+package abide
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var reporterResults = []Result{
+	{ID: "matched", Test: "TestA", Status: StatusMatched, Stored: "A", Actual: "A"},
+	{ID: "mismatched", Test: "TestA", Status: StatusMismatched, Stored: "A\nB", Actual: "A\nC"},
+	{ID: "missing", Status: StatusNew, Actual: "D"},
+}
+
+// recordingReporter records the results it reports.
+type recordingReporter struct {
+	pkg     string
+	results []Result
+}
+
+func (r *recordingReporter) Report(pkg string, results []Result) error {
+	r.pkg, r.results = pkg, results
+	return nil
+}
+
+// reporterFunc is a Reporter calling the function.
+type reporterFunc func(pkg string, results []Result) error
+
+func (f reporterFunc) Report(pkg string, results []Result) error {
+	return f(pkg, results)
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewJUnitReporter(&buf).Report("api", reporterResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitSuites
+	err = xml.Unmarshal(buf.Bytes(), &suites)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suite := suites.Suites[0]
+	if suite.Name != "api" || suite.Tests != 3 || suite.Failures != 2 {
+		t.Fatalf("Unexpected test suite %+v.", suite)
+	}
+	if c := suite.Cases[0]; c.Name != "matched" || c.Classname != "api.TestA" || c.Failure != nil {
+		t.Fatalf("Unexpected test case %+v.", c)
+	}
+	if f := suite.Cases[1].Failure; f == nil || f.Type != "mismatched" || f.Body != "A\n[-B-]{+C+}" {
+		t.Fatalf("Unexpected failure %+v.", f)
+	}
+	if c := suite.Cases[2]; c.Classname != "api" || c.Failure == nil || c.Failure.Body != "D" {
+		t.Fatalf("Unexpected test case %+v.", c)
+	}
+}
+
+func TestTAPReporter(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewTAPReporter(&buf).Report("api", reporterResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `TAP version 13
+1..3
+ok 1 - api: matched (matched)
+not ok 2 - api: mismatched (mismatched)
+  ---
+  message: "existing snapshot does not match"
+  test: "TestA"
+  diff: |-
+    A
+    [-B-]{+C+}
+  ...
+not ok 3 - api: missing (new)
+  ---
+  message: "no snapshot exists"
+  actual: |-
+    D
+  ...
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected %q, instead got %q.", expected, buf.String())
+	}
+}
+
+func TestCleanupReporters(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("existing", "A")
+
+	recorder := &recordingReporter{}
+	// reporters are called without holding any lock of abide
+	reentrant := reporterFunc(func(string, []Result) error {
+		_, err := Match("existing", String("B"))
+		if err != nil {
+			return err
+		}
+		return Flush()
+	})
+	Reporters = []Reporter{recorder, reentrant}
+	defer func() { Reporters = nil }()
+
+	dir := testingTempDir(t)
+	withArguments(updateNone, true, func() {
+		args.junitPath = filepath.Join(dir, "junit.xml")
+		args.tapPath = filepath.Join(dir, "results.tap")
+
+		_, err := Match("existing", String("B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = Cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if len(recorder.results) != 1 || recorder.results[0].Status != StatusMismatched {
+		t.Fatalf("Expected the reporter to receive the results, instead got %+v.", recorder.results)
+	}
+
+	for name, expected := range map[string]string{"junit.xml": "<testcase name=\"existing\"", "results.tap": "not ok 1"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), expected) {
+			t.Fatalf("Expected %s to contain %q, instead got %s.", name, expected, data)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package abide
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// resultsFile is the JSON document written by the JSON reporter.
+type resultsFile struct {
+	// Package is the name of the package under test.
+	Package string `json:"package"`
+	// Results are the results of the snapshots asserted, ordered by id.
+	Results []Result `json:"results"`
+}
+
+// jsonReporter writes results as a JSON document.
+type jsonReporter struct {
+	w io.Writer
+}
+
+// NewJSONReporter returns a Reporter writing the results of a package to w
+// as a JSON document, the format of the file requested by -abide.results.
+func NewJSONReporter(w io.Writer) Reporter {
+	return &jsonReporter{w: w}
+}
+
+// Report implements Reporter.
+func (r *jsonReporter) Report(pkg string, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+
+	data, err := json.MarshalIndent(resultsFile{Package: pkg, Results: results}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = r.w.Write(append(data, '\n'))
+	return err
+}
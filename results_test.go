@@ -0,0 +1,87 @@
+package abide
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testingTempDir returns a directory removed once t finishes, like
+// t.TempDir, which requires Go 1.15.
+func testingTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "abide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestWriteResults(t *testing.T) {
+	defer testingCleanup()
+	existing := testingSnapshot("existing", "A")
+
+	path := filepath.Join(testingTempDir(t), "results.json")
+	withArguments(updateNone, true, func() {
+		args.resultsPath = path
+
+		Assert(&harness{name: "TestHarness"}, "existing", String("B"))
+		_, err := Match("missing", String("C"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = Cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var file resultsFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file.Results) != 2 {
+		t.Fatalf("Expected 2 results, instead got %s.", data)
+	}
+	if r := file.Results[0]; r.ID != "existing" || r.Test != "TestHarness" || r.Path != existing.path ||
+		r.Status != StatusMismatched || r.Stored != "A" || r.Actual != "B" || r.Diff != "[-A-]{+B+}" {
+		t.Fatalf("Unexpected result %+v.", r)
+	}
+	if r := file.Results[1]; r.ID != "missing" || r.Status != StatusNew || r.Path != "" || r.Actual != "C" {
+		t.Fatalf("Unexpected result %+v.", r)
+	}
+}
+
+func TestStatusText(t *testing.T) {
+	for status := StatusNew; status <= StatusUpdated; status++ {
+		text, err := status.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded Status
+		err = decoded.UnmarshalText(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != status {
+			t.Fatalf("Expected %s, instead got %s.", status, decoded)
+		}
+	}
+
+	var s Status
+	if s.UnmarshalText([]byte("unknown")) == nil {
+		t.Fatal("Expected an error for an unknown status.")
+	}
+}
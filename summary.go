@@ -0,0 +1,109 @@
+package abide
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Summary describes the snapshots of a package asserted, pruned and found
+// obsolete by a run, as returned by CleanupWithSummary.
+type Summary struct {
+	// Package is the name of the package under test.
+	Package string
+	// Matched is the number of snapshots which matched.
+	Matched int
+	// Created are the ids of the snapshots created.
+	Created []string
+	// Updated are the ids of the snapshots updated.
+	Updated []string
+	// New are the ids of missing snapshots which were not created.
+	New []string
+	// Mismatched are the ids of snapshots which did not match, and were
+	// not updated.
+	Mismatched []string
+	// Pruned are the ids of the unused snapshots removed.
+	Pruned []string
+	// Obsolete are the ids of the snapshots no test asserted, only
+	// determined in full, unfiltered runs.
+	Obsolete []string
+}
+
+// Failed reports whether any snapshot is missing or did not match.
+func (s Summary) Failed() bool {
+	return len(s.New) > 0 || len(s.Mismatched) > 0
+}
+
+// String returns a line of counts, followed by a line listing the ids
+// of every non-empty bucket other than the matched snapshots.
+func (s Summary) String() string {
+	buckets := []struct {
+		name string
+		ids  []string
+	}{
+		{"created", s.Created},
+		{"updated", s.Updated},
+		{"new", s.New},
+		{"mismatched", s.Mismatched},
+		{"pruned", s.Pruned},
+		{"obsolete", s.Obsolete},
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "abide: %s: %d matched", s.Package, s.Matched)
+	for _, bucket := range buckets {
+		fmt.Fprintf(&b, ", %d %s", len(bucket.ids), bucket.name)
+	}
+	b.WriteString("\n")
+
+	for _, bucket := range buckets {
+		if len(bucket.ids) > 0 {
+			fmt.Fprintf(&b, "  %s: %s\n", bucket.name, strings.Join(bucket.ids, ", "))
+		}
+	}
+	return b.String()
+}
+
+// add counts r in its bucket.
+func (s *Summary) add(r Result) {
+	switch r.Status {
+	case StatusMatched:
+		s.Matched++
+	case StatusCreated:
+		s.Created = append(s.Created, r.ID)
+	case StatusUpdated:
+		s.Updated = append(s.Updated, r.ID)
+	case StatusNew:
+		s.New = append(s.New, r.ID)
+	case StatusMismatched:
+		s.Mismatched = append(s.Mismatched, r.ID)
+	}
+}
+
+// sort orders the ids of every bucket.
+func (s *Summary) sort() {
+	for _, ids := range [][]string{s.Created, s.Updated, s.New, s.Mismatched, s.Pruned, s.Obsolete} {
+		sort.Strings(ids)
+	}
+}
+
+// isEmpty reports whether the run neither asserted, pruned nor found
+// obsolete snapshots.
+func (s Summary) isEmpty() bool {
+	return s.Matched == 0 && len(s.Created) == 0 && len(s.Updated) == 0 && len(s.New) == 0 &&
+		len(s.Mismatched) == 0 && len(s.Pruned) == 0 && len(s.Obsolete) == 0
+}
+
+// recordResult records r for the summary of the run. A snapshot asserted
+// more than once keeps its first failing result, and a match never
+// replaces a snapshot created or updated earlier.
+func recordResult(r Result) {
+	allSnapMutex.Lock()
+	defer allSnapMutex.Unlock()
+
+	id := snapshotID(r.ID)
+	if prev, ok := results[id]; ok && (prev.Failed() || r.Status == StatusMatched) {
+		return
+	}
+	results[id] = r
+}
@@ -0,0 +1,80 @@
+package abide
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCleanupWithSummary(t *testing.T) {
+	defer testingCleanup()
+	_ = testingSnapshot("matched", "A")
+	_ = testingSnapshot("mismatched", "A")
+	_ = testingSnapshot("unused", "A")
+
+	var summary Summary
+	withArguments(updateNone, false, func() {
+		for id, value := range map[string]string{"matched": "A", "mismatched": "B", "missing": "C"} {
+			_, err := Match(id, String(value))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var err error
+		summary, err = CleanupWithSummary()
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	pkg, err := getTestingPackage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Summary{
+		Package:    pkg,
+		Matched:    1,
+		New:        []string{"missing"},
+		Mismatched: []string{"mismatched"},
+		Obsolete:   []string{"unused"},
+	}
+	if !reflect.DeepEqual(expected, summary) {
+		t.Fatalf("Expected summary %+v, instead got %+v.", expected, summary)
+	}
+	if !summary.Failed() {
+		t.Fatal("Expected the summary to fail.")
+	}
+}
+
+func TestSummaryString(t *testing.T) {
+	s := Summary{
+		Package: "api",
+		Matched: 3,
+		Created: []string{"a", "b"},
+		Pruned:  []string{"c"},
+	}
+
+	expected := "abide: api: 3 matched, 2 created, 0 updated, 0 new, 0 mismatched, 1 pruned, 0 obsolete\n" +
+		"  created: a, b\n" +
+		"  pruned: c\n"
+	if s.String() != expected {
+		t.Fatalf("Expected %q, instead got %q.", expected, s.String())
+	}
+}
+
+func TestRecordResult(t *testing.T) {
+	defer testingCleanup()
+
+	recordResult(Result{ID: "1", Status: StatusCreated})
+	recordResult(Result{ID: "1", Status: StatusMatched})
+	recordResult(Result{ID: "2", Status: StatusMismatched})
+	recordResult(Result{ID: "2", Status: StatusUpdated})
+
+	if s := results["1"].Status; s != StatusCreated {
+		t.Fatalf("Expected a match not to replace %s, instead got %s.", StatusCreated, s)
+	}
+	if s := results["2"].Status; s != StatusMismatched {
+		t.Fatalf("Expected the failing result to be kept, instead got %s.", s)
+	}
+}